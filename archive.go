@@ -0,0 +1,209 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"compress/gzip"
+	"compress/lzw"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxPendingArchives is the number of rotated files waiting for compression,
+// a rotation waits when more files are rotated
+const maxPendingArchives = 8
+
+// archiveSuffix is the suffix of an archive being written
+const archiveSuffix = ".tmp"
+
+// archiveJob is a rotated file to compress, name is updated by shift when
+// the numbered files are renamed and cleared when the file is replaced
+type archiveJob struct {
+	name string
+}
+
+// queueArchive queues the rotated file name for compression
+func (l *RotateLogger) queueArchive(name string) {
+	job := &archiveJob{name: name}
+	l.pendingMu.Lock()
+	l.pending[name] = job
+	l.pendingMu.Unlock()
+	l.archiving.Add(1)
+	l.archives <- job
+}
+
+// archiver compresses the recovered files, then the queued rotated files
+// one at a time
+func (l *RotateLogger) archiver(recovered []*archiveJob) {
+	for _, job := range recovered {
+		l.archive(job)
+		l.archiving.Done()
+	}
+	for job := range l.archives {
+		l.archive(job)
+		l.archiving.Done()
+	}
+}
+
+// recoverArchives removes the archives left half-written by a previous
+// process and returns the rotated files it left uncompressed, they are
+// compressed by the archiver before the queued files
+func (l *RotateLogger) recoverArchives() []*archiveJob {
+	files, err := l.rotatedFiles(true)
+	if err != nil {
+		backendError(nil, l, OpArchive, err)
+		return nil
+	}
+	var jobs []*archiveJob
+	for _, f := range files {
+		switch {
+		case strings.HasSuffix(f.name, archiveSuffix):
+			if err := os.Remove(f.name); err != nil {
+				backendError(nil, l, OpArchive, err)
+			}
+		case l.compress > NoCompress && !compressed(f.name):
+			job := &archiveJob{name: f.name}
+			l.pending[f.name] = job
+			l.archiving.Add(1)
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// isArchiving reports whether name is queued or being compressed
+func (l *RotateLogger) isArchiving(name string) bool {
+	l.pendingMu.Lock()
+	defer l.pendingMu.Unlock()
+	_, ok := l.pending[name]
+	return ok
+}
+
+// renamePending records that the pending file name was renamed to newName
+// by shift, the caller holds pendingMu
+func (l *RotateLogger) renamePending(name, newName string) {
+	if job, ok := l.pending[name]; ok {
+		delete(l.pending, name)
+		job.name = newName
+		l.pending[newName] = job
+	}
+}
+
+// compressed reports whether name has the suffix of a compress method
+func compressed(name string) bool {
+	for _, c := range []CompressMethod{GZIP, Zlib, LZW} {
+		if strings.HasSuffix(name, c.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// archive compresses the rotated file of job to name.(gz/zlib/lz) and
+// removes it. The archive is written to a temporary file renamed once
+// complete to the name the rotated file has then. The errors are reported
+// to the default error handler, the archiver does not write records.
+func (l *RotateLogger) archive(job *archiveJob) {
+
+	l.pendingMu.Lock()
+	name := job.name
+	if len(name) == 0 {
+		// replaced by a newer file before it was compressed
+		l.pendingMu.Unlock()
+		return
+	}
+	in, err := os.Open(name)
+	l.pendingMu.Unlock()
+
+	// fail to open, such as file not exist or some other file system errors
+	if err != nil {
+		l.archived(job, "")
+		backendError(nil, l, OpArchive, fmt.Errorf("error opening file %s : %v", name, err))
+		return
+	}
+
+	defer in.Close()
+
+	tmpFileName := fmt.Sprintf("%s%s%s", name, l.compress, archiveSuffix)
+
+	out, err := os.OpenFile(tmpFileName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		l.archived(job, "")
+		backendError(nil, l, OpArchive, fmt.Errorf("error opening file %s : %v", tmpFileName, err))
+		return
+	}
+
+	// need compress, try to open
+	var w io.WriteCloser
+	switch l.compress {
+	case GZIP:
+		w = gzip.NewWriter(out)
+	case Zlib:
+		w = zlib.NewWriter(out)
+	case LZW:
+		w = lzw.NewWriter(out, lzw.MSB, 8)
+	default:
+		w = out
+	}
+
+	_, err = io.Copy(w, in)
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		if info, serr := in.Stat(); serr == nil {
+			// the rotated file keeps its age
+			_ = os.Chtimes(tmpFileName, info.ModTime(), info.ModTime())
+		}
+	}
+	in.Close()
+
+	if err != nil {
+		l.archived(job, "")
+		_ = os.Remove(tmpFileName)
+		backendError(nil, l, OpArchive, fmt.Errorf("error commpressing file %s : %v", name, err))
+		return
+	}
+	if err := l.archived(job, tmpFileName); err != nil {
+		backendError(nil, l, OpArchive, err)
+	}
+}
+
+// archived removes job from the pending files. If tmpFileName is not
+// empty, it is renamed after the current name of the rotated file which
+// is removed, or removed itself if the rotated file was replaced.
+func (l *RotateLogger) archived(job *archiveJob, tmpFileName string) error {
+	l.pendingMu.Lock()
+	defer l.pendingMu.Unlock()
+	name := job.name
+	if len(name) > 0 {
+		delete(l.pending, name)
+	}
+	if len(tmpFileName) == 0 {
+		return nil
+	}
+	if len(name) == 0 {
+		// replaced by a newer file while it was compressed
+		return os.Remove(tmpFileName)
+	}
+	compressedFileName := fmt.Sprintf("%s%s", name, l.compress)
+	if err := os.Rename(tmpFileName, compressedFileName); err != nil {
+		_ = os.Remove(tmpFileName)
+		return fmt.Errorf("error commpressing file %s : %v", name, err)
+	}
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("error removing file %s : %v", name, err)
+	}
+	return nil
+}
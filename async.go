@@ -5,31 +5,84 @@
 package log
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // AsyncLog an async logger
 type AsyncLog struct {
-	stopped uint32
+	writeFailures
+	dropped uint64 // number of dropped records, kept 64-bit aligned after writeFailures
 	out     io.Writer
 	mu      sync.Mutex
 	handler Handler
 	istty   bool
-	queue   chan *Record
+	format  Formatter
+	queue   *recordQueue
+	summary time.Duration // interval of the dropped records summary
 	stop    chan struct{} // Notify closing
+
+	reportMu sync.Mutex // serializes the dropped records summaries
+	reported uint64     // number of dropped records reported by the summaries
+
+	batch    []byte        // records formatted by the writing goroutine
+	last     *Record       // last record in batch
+	maxBatch int           // size of batch written at once
+	linger   time.Duration // time to wait for more records before writing batch
 }
 
+// AsyncOptions configures the queue of an async backend
+type AsyncOptions struct {
+	// QueueSize is the number of records the queue holds, 1024 if 0
+	QueueSize int
+
+	// Overflow is the policy applied to the records logged to a full queue
+	Overflow OverflowPolicy
+
+	// Timeout is the time a record waits for room in the queue with OverflowTimeout
+	Timeout time.Duration
+
+	// Keep is the least severe level never dropped, ERROR if 0. Records of
+	// Keep or more severe levels wait for room in the queue.
+	Keep Level
+
+	// SummaryInterval is the interval of the WARN record reporting the
+	// number of records dropped since the last one, one minute if 0 and
+	// no summary is written if negative. The summary is never dropped and
+	// a last one is written by Close.
+	SummaryInterval time.Duration
+
+	// MaxBatchBytes is the size of the formatted records collected by the
+	// writing goroutine before they are written with one Write, 64KB if 0
+	MaxBatchBytes int
+
+	// MaxLinger is the time the writing goroutine waits for more records
+	// before writing a batch, the queued records are written at once if 0
+	MaxLinger time.Duration
+}
+
+// default batch size of AsyncLog and RotateLogger
+const defaultBatchBytes = 64 * 1024
+
 // NewAsyncLogger creates a new async logger with a io.Writer
 func NewAsyncLogger(w io.Writer, prefix string, flag int) *Logger {
+	return NewAsyncLoggerWithOptions(w, prefix, flag, AsyncOptions{})
+}
+
+// NewAsyncLoggerWithOptions creates a new async logger with a io.Writer
+// and the given queue options
+func NewAsyncLoggerWithOptions(w io.Writer, prefix string, flag int, opts AsyncOptions) *Logger {
 
 	l := &Logger{
 		level:   DEBUG,
 		mu:      sync.Mutex{},
 		prefix:  prefix,
 		flag:    flag,
-		backend: NewAsyncBackend(w),
+		backend: NewAsyncBackendWithOptions(w, opts),
 		index:   0,
 		name:    procName(),
 	}
@@ -41,16 +94,42 @@ func NewAsyncLogger(w io.Writer, prefix string, flag int) *Logger {
 
 // NewAsyncBackend creates a new async backend
 func NewAsyncBackend(w io.Writer) Backend {
+	return NewAsyncBackendWithOptions(w, AsyncOptions{})
+}
+
+// NewAsyncBackendWithOptions creates a new async backend with the given queue options
+func NewAsyncBackendWithOptions(w io.Writer, opts AsyncOptions) Backend {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.Keep == none {
+		opts.Keep = ERROR
+	}
+	if opts.SummaryInterval == 0 {
+		opts.SummaryInterval = time.Minute
+	}
+	if opts.MaxBatchBytes <= 0 {
+		opts.MaxBatchBytes = defaultBatchBytes
+	}
 	handler, ok := w.(Handler)
 	return &AsyncLog{
 		out:     w,
 		handler: handler,
 		istty:   ok && isatty(handler.Fd()),
-		queue:   make(chan *Record, 1024),
+		queue:   newRecordQueue(opts.QueueSize, opts.Overflow, opts.Timeout, opts.Keep),
+		summary: opts.SummaryInterval,
 		stop:    make(chan struct{}),
+
+		maxBatch: opts.MaxBatchBytes,
+		linger:   opts.MaxLinger,
 	}
 }
 
+// Dropped returns the number of records dropped because the queue was full
+func (l *AsyncLog) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
 // Writer returns the io.Writer of current Syslog
 func (l *AsyncLog) Writer() io.Writer {
 	l.mu.Lock()
@@ -75,23 +154,110 @@ func (l *AsyncLog) write(data []byte) error {
 }
 
 func (l *AsyncLog) log(r *Record) {
-	if atomic.LoadUint32(&l.stopped) == 0 {
-		l.queue <- r
+	if dropped := l.queue.put(r); dropped > 0 {
+		atomic.AddUint64(&l.dropped, uint64(dropped))
 	}
 }
 
 func (l *AsyncLog) start() {
-	for r := range l.queue {
-		writeLog(l, r)
+	if l.summary > 0 {
+		go l.summarize()
 	}
+	l.queue.drain(func() time.Duration { return l.linger }, l.add, l.flushBatch)
 	close(l.stop)
 }
 
-// Flush the current log backend
-func (l *AsyncLog) Flush() {
-	atomic.StoreUint32(&l.stopped, 1)
-	close(l.queue)
-	<-l.stop
+// add formats r into the batch, the batch is written if it is full
+func (l *AsyncLog) add(r *Record) (full bool) {
+	if r.flushed != nil {
+		l.flushBatch()
+		r.flushed <- syncWriter(l.out)
+		return true
+	}
+	if consoleColors && r.colored(l) && !l.isatty() {
+		// console attributes only apply to the writes following them
+		l.flushBatch()
+		writeLog(l, r)
+		return false
+	}
+	l.batch = r.appendTo(l, l.batch, r.ansiColor(l))
+	l.last = r
+	if len(l.batch) >= l.maxBatch {
+		l.flushBatch()
+		return true
+	}
+	return false
+}
+
+// flushBatch writes the batch with one Write
+func (l *AsyncLog) flushBatch() {
+	if len(l.batch) > 0 {
+		if err := l.write(l.batch); err != nil {
+			backendError(l.last, l, OpWrite, err)
+		}
+	}
+	l.batch, l.last = l.batch[:0], nil
+}
+
+// summarize reports the dropped records every summary interval
+func (l *AsyncLog) summarize() {
+	ticker := time.NewTicker(l.summary)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.report()
+		}
+	}
+}
+
+// report queues a record with the number of records dropped since the
+// last report, the record is not subject to the overflow policy
+func (l *AsyncLog) report() {
+	l.reportMu.Lock()
+	defer l.reportMu.Unlock()
+	dropped := atomic.LoadUint64(&l.dropped)
+	if dropped == l.reported {
+		return
+	}
+	last := l.queue.lastRecord()
+	if last == nil {
+		return
+	}
+	r := last.derive(WARN, fmt.Sprintf("%d records dropped", dropped-l.reported))
+	r.kept = true
+	l.reported = dropped
+	l.log(r)
+}
+
+// Flush waits until the records queued so far are written
+func (l *AsyncLog) Flush(ctx context.Context) error {
+	return flushQueue(ctx, l.queue, l.stop)
+}
+
+// Close writes the queued records and the last dropped records summary
+// and stops current backend, the writer is not closed
+func (l *AsyncLog) Close(ctx context.Context) error {
+	if l.summary > 0 {
+		l.report()
+	}
+	l.queue.close()
+	return waitStop(ctx, l.stop)
+}
+
+// SetFormatter sets the formatter of current backend
+func (l *AsyncLog) SetFormatter(f Formatter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = f
+}
+
+func (l *AsyncLog) formatter() Formatter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.format
 }
 
 func (l *AsyncLog) isatty() bool {
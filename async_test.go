@@ -0,0 +1,286 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter blocks the first write until release is closed, the
+// writes are sent to written
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+	written chan string
+	once    sync.Once
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+		written: make(chan string, 64),
+	}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.started)
+		<-w.release
+	})
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	select {
+	case w.written <- string(p):
+	default:
+	}
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// waitForPut waits until n records logged to l wait for room in its queue
+func waitForPut(l *Logger, n int) {
+	q := l.sink().(*AsyncLog).queue
+	for q.waiters() < n {
+		runtime.Gosched()
+	}
+}
+
+// newAsyncTestLogger creates an async logger without flags writing to w,
+// it is closed at the end of the test
+func newAsyncTestLogger(t *testing.T, w io.Writer, opts AsyncOptions) *Logger {
+	t.Helper()
+	l := NewAsyncLoggerWithOptions(w, "", 0, opts)
+	t.Cleanup(func() { _ = l.Close(context.Background()) })
+	return l
+}
+
+func testOverflow(t *testing.T, policy OverflowPolicy, blocks bool, want string, wantDropped uint64) {
+	w := newBlockingWriter()
+	l := newAsyncTestLogger(t, w, AsyncOptions{
+		QueueSize:       2,
+		Overflow:        policy,
+		Timeout:         time.Millisecond,
+		SummaryInterval: -1,
+	})
+
+	l.Info("1")
+	<-w.started
+	l.Error("2")
+	l.Info("3")
+	l.Info("4")
+	done := make(chan struct{})
+	go func() {
+		l.Error("5")
+		close(done)
+	}()
+	if blocks {
+		// 5 is not dropped, it waits for the writer
+		waitForPut(l, 1)
+	} else {
+		<-done
+	}
+	close(w.release)
+	<-done
+	l.Flush()
+
+	if got := strings.Replace(w.String(), "\n", " ", -1); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	if dropped := l.sink().(*AsyncLog).Dropped(); dropped != wantDropped {
+		t.Errorf("dropped %d records; want %d", dropped, wantDropped)
+	}
+}
+
+func TestAsyncOverflow(t *testing.T) {
+	testOverflow(t, OverflowDropNewest, true, "[ INFO] 1 [ERROR] 2 [ INFO] 3 [ERROR] 5 ", 1)
+	testOverflow(t, OverflowDropOldest, false, "[ INFO] 1 [ERROR] 2 [ERROR] 5 ", 2)
+	testOverflow(t, OverflowTimeout, true, "[ INFO] 1 [ERROR] 2 [ INFO] 3 [ERROR] 5 ", 1)
+}
+
+func TestAsyncDropSummary(t *testing.T) {
+	w := newBlockingWriter()
+	l := newAsyncTestLogger(t, w, AsyncOptions{
+		QueueSize:       1,
+		Overflow:        OverflowDropNewest,
+		SummaryInterval: time.Millisecond,
+	})
+
+	l.Info("1")
+	<-w.started
+	l.Info("2")
+	l.Info("3")
+	l.Info("4")
+	close(w.release)
+	for summary := false; !summary; {
+		select {
+		case data := <-w.written:
+			summary = strings.Contains(data, "records dropped")
+		case <-time.After(5 * time.Second):
+			t.Fatal("no dropped records summary")
+		}
+	}
+	l.Flush()
+	if want := "[ INFO] 1\n[ INFO] 2\n[ WARN] 2 records dropped\n"; w.String() != want {
+		t.Errorf("got %q; want %q", w.String(), want)
+	}
+}
+
+func TestAsyncCloseSummary(t *testing.T) {
+	w := newBlockingWriter()
+	l := newAsyncTestLogger(t, w, AsyncOptions{
+		QueueSize:       1,
+		Overflow:        OverflowDropNewest,
+		SummaryInterval: time.Hour,
+	})
+
+	l.Info("1")
+	<-w.started
+	l.Info("2")
+	l.Info("3")
+	l.Info("4")
+	closed := make(chan error)
+	go func() {
+		closed <- l.Close(context.Background())
+	}()
+	// the summary waits for room in the full queue
+	waitForPut(l, 1)
+	close(w.release)
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	if want := "[ INFO] 1\n[ INFO] 2\n[ WARN] 2 records dropped\n"; w.String() != want {
+		t.Errorf("got %q; want %q", w.String(), want)
+	}
+}
+
+// countingWriter records the data of each write
+type countingWriter struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestAsyncBatch(t *testing.T) {
+	w := &countingWriter{}
+	l := newAsyncTestLogger(t, w, AsyncOptions{
+		MaxBatchBytes:   20,
+		MaxLinger:       time.Hour,
+		SummaryInterval: -1,
+	})
+
+	for i := 0; i < 5; i++ {
+		l.Info(i)
+	}
+	l.Flush()
+
+	want := []string{"[ INFO] 0\n[ INFO] 1\n", "[ INFO] 2\n[ INFO] 3\n", "[ INFO] 4\n"}
+	if len(w.writes) != len(want) {
+		t.Fatalf("got writes %q; want %q", w.writes, want)
+	}
+	for i := range want {
+		if w.writes[i] != want[i] {
+			t.Errorf("write %d: got %q; want %q", i, w.writes[i], want[i])
+		}
+	}
+}
+
+func TestAsyncLingerBound(t *testing.T) {
+	q := newRecordQueue(4, OverflowBlock, 0, ERROR)
+	q.put(&Record{})
+	flushed := make(chan struct{}, 1)
+	// each record written queues another one, the queue is never empty
+	go q.drain(func() time.Duration { return 10 * time.Millisecond }, func(*Record) bool {
+		q.put(&Record{})
+		return false
+	}, func() {
+		select {
+		case flushed <- struct{}{}:
+		default:
+		}
+	})
+	defer q.close()
+	select {
+	case <-flushed:
+	case <-time.After(5 * time.Second):
+		t.Error("batch not ended by MaxLinger under steady load")
+	}
+}
+
+func TestAsyncFlushClose(t *testing.T) {
+	w := newBlockingWriter()
+	l := newAsyncTestLogger(t, w, AsyncOptions{SummaryInterval: -1})
+
+	l.Info("1")
+	<-w.started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := l.FlushContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("flush of a blocked backend returned %v", err)
+	}
+	close(w.release)
+	l.Flush()
+	l.Info("2")
+	if err := l.FlushContext(context.Background()); err != nil {
+		t.Errorf("flush returned %v", err)
+	}
+	if want := "[ INFO] 1\n[ INFO] 2\n"; w.String() != want {
+		t.Errorf("got %q; want %q", w.String(), want)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := l.Close(context.Background()); err != nil {
+			t.Errorf("close %d returned %v", i, err)
+		}
+	}
+	l.Info("3")
+	if err := l.FlushContext(context.Background()); err != nil {
+		t.Errorf("flush after close returned %v", err)
+	}
+	if want := "[ INFO] 1\n[ INFO] 2\n"; w.String() != want {
+		t.Errorf("got %q after close; want %q", w.String(), want)
+	}
+}
+
+func TestFlushFullQueue(t *testing.T) {
+	q := newRecordQueue(2, OverflowDropOldest, 0, ERROR)
+	first, second := &Record{level: INFO}, &Record{level: INFO}
+	q.put(first)
+	q.put(second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := flushQueue(ctx, q, make(chan struct{})); err != context.DeadlineExceeded {
+		t.Errorf("flush of a full queue returned %v", err)
+	}
+	records, _ := q.takeAll(nil, time.Time{})
+	if len(records) != 2 || records[0] != first || records[1] != second {
+		t.Errorf("flush marker evicted a record: got %v", records)
+	}
+	q.put(&Record{level: INFO})
+	if records, _ := q.takeAll(nil, time.Time{}); len(records) != 1 || records[0].flushed != nil {
+		t.Errorf("cancelled flush marker queued: got %v", records)
+	}
+}
@@ -5,7 +5,7 @@
 package log
 
 import (
-	"fmt"
+	"context"
 	"io"
 	"os"
 )
@@ -23,8 +23,20 @@ type Backend interface {
 	// SetWriter sets the io.Writer of current backend for compatible with golang's log package
 	SetWriter(w io.Writer)
 
-	// Flush flushes current logging backend
-	Flush()
+	// Flush waits until the records logged so far are written and the
+	// files are synced, the backend keeps working after Flush
+	Flush(ctx context.Context) error
+
+	// Close flushes and stops current logging backend, the records logged
+	// after Close may be discarded. Close can be called more than once, it
+	// returns ctx.Err() if ctx is done before the backend is stopped.
+	Close(ctx context.Context) error
+
+	// SetFormatter sets the formatter of current backend, overriding the formatter of the logger
+	SetFormatter(f Formatter)
+
+	// returns the formatter of current backend, nil if not set
+	formatter() Formatter
 
 	// log an log record
 	log(r *Record)
@@ -51,9 +63,47 @@ func closeBackend(backend Backend) {
 			// do not close this
 		default:
 			if err := file.Close(); err != nil {
-				_, _ = fmt.Fprintf(os.Stderr, "error close file : %v\n", err)
+				backendError(nil, backend, OpClose, err)
 			}
 		}
 	default:
 	}
 }
+
+// syncWriter commits the data written to w to stable storage if w is a regular file
+func syncWriter(w io.Writer) error {
+	f, ok := w.(*os.File)
+	if !ok {
+		return nil
+	}
+	if info, err := f.Stat(); err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	return f.Sync()
+}
+
+// flushQueue queues a flush marker to q and waits until the records
+// queued before it are written, stop is closed when the queue is drained
+func flushQueue(ctx context.Context, q *recordQueue, stop chan struct{}) error {
+	// the marker never evicts a record and is given up with ctx
+	marker := &Record{flushed: make(chan error, 1), kept: true}
+	go q.putUntil(marker, ctx.Done())
+	select {
+	case err := <-marker.flushed:
+		return err
+	case <-stop:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitStop waits until stop is closed or ctx is done
+func waitStop(ctx context.Context, stop chan struct{}) error {
+	select {
+	case <-stop:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
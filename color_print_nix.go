@@ -6,7 +6,11 @@
 
 package log
 
+// consoleColors is true if the colors of a console which is not a tty are
+// set by console attributes instead of ANSI sequences
+const consoleColors = false
+
 // colorful writes the message with console colors under windows
 func (r *Record) colorful(backend Backend, bold bool) {
-	_ = backend.write(r.msgBuf())
+	r.writeTo(backend, "")
 }
@@ -34,39 +34,35 @@ const (
 )
 
 var (
-	colorsW = []uint16{
-		FATAL: fgMagenta,
-		ERROR: fgRed,
-		WARN:  fgYellow,
-		INFO:  fgGreen,
-		DEBUG: fgCyan,
-		none:  fgWhite,
-	}
-
-	boldColorsW = []uint16{
-		FATAL: fgMagenta | fgIntensity,
-		ERROR: fgRed | fgIntensity,
-		WARN:  fgYellow | fgIntensity,
-		INFO:  fgGreen | fgIntensity,
-		DEBUG: fgCyan | fgIntensity,
-		none:  fgWhite | fgIntensity,
+	// colorsW maps the ANSI colors to console attributes
+	colorsW = map[Color]uint16{
+		ColorBlack:   fgBlack,
+		ColorRed:     fgRed,
+		ColorGreen:   fgGreen,
+		ColorYellow:  fgYellow,
+		ColorBlue:    fgBlue,
+		ColorMagenta: fgMagenta,
+		ColorCyan:    fgCyan,
+		ColorWhite:   fgWhite,
 	}
 
 	colorResetW uint16 = fgWhite
 )
 
+// consoleColors is true if the colors of a console which is not a tty are
+// set by console attributes instead of ANSI sequences
+const consoleColors = true
+
 // colorful writes the message with console colors under windows
 func (r *Record) colorful(backend Backend, bold bool) {
 
-	var col uint16
+	col := colorsW[levelColor(r.level)]
 	if bold {
-		col = boldColorsW[r.level]
-	} else {
-		col = colorsW[r.level]
+		col |= fgIntensity
 	}
 
 	setConsoleTextAttribute(backend.fd(), col)
-	_ = backend.write(r.msgBuf())
+	r.writeTo(backend, "")
 	setConsoleTextAttribute(backend.fd(), colorResetW)
 }
 
@@ -0,0 +1,159 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// contextKey is the key of the logger stored in a context
+type contextKey struct{}
+
+// ContextExtractor returns the fields of the values of ctx to be added to
+// the records logged with ctx, such as a trace id, span id or tenant
+type ContextExtractor func(ctx context.Context) []Field
+
+// registeredExtractor is a registered ContextExtractor, its address
+// identifies the registration
+type registeredExtractor struct {
+	extract ContextExtractor
+}
+
+var (
+	extractorsMu sync.Mutex   // serializes the updates of extractors
+	extractors   atomic.Value // holds []*registeredExtractor
+)
+
+// RegisterContextExtractor registers an extractor called for each record
+// logged by the XxxContext functions, the returned function unregisters it
+func RegisterContextExtractor(extractor ContextExtractor) (unregister func()) {
+	entry := &registeredExtractor{extract: extractor}
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	current, _ := extractors.Load().([]*registeredExtractor)
+	updated := make([]*registeredExtractor, 0, len(current)+1)
+	updated = append(updated, current...)
+	extractors.Store(append(updated, entry))
+	return func() {
+		extractorsMu.Lock()
+		defer extractorsMu.Unlock()
+		current, _ := extractors.Load().([]*registeredExtractor)
+		updated := make([]*registeredExtractor, 0, len(current))
+		for _, e := range current {
+			if e != entry {
+				updated = append(updated, e)
+			}
+		}
+		extractors.Store(updated)
+	}
+}
+
+// contextFields returns the fields of all registered extractors for ctx
+func contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	var fields []Field
+	current, _ := extractors.Load().([]*registeredExtractor)
+	for _, e := range current {
+		fields = append(fields, e.extract(ctx)...)
+	}
+	return fields
+}
+
+// NewContext returns a copy of ctx carrying l
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, the std logger if there is none
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+	return std()
+}
+
+func (l *Logger) logContext(ctx context.Context, level Level, msg string, keyvals ...interface{}) {
+	if l.enabled(level, 2) {
+		l.output(4, level, writeModeLog, nil, joinFields(contextFields(ctx), makeFields(keyvals)), msg)
+	}
+}
+
+// LogContext prints log at the given level with the fields extracted from ctx and the given key/value pairs.
+func (l *Logger) LogContext(ctx context.Context, level Level, msg string, keyvals ...interface{}) {
+	l.logContext(ctx, level, msg, keyvals...)
+}
+
+// TraceContext prints trace log with the fields extracted from ctx and the given key/value pairs.
+func (l *Logger) TraceContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.logContext(ctx, TRACE, msg, keyvals...)
+}
+
+// DebugContext prints debug log with the fields extracted from ctx and the given key/value pairs.
+func (l *Logger) DebugContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.logContext(ctx, DEBUG, msg, keyvals...)
+}
+
+// InfoContext prints info log with the fields extracted from ctx and the given key/value pairs.
+func (l *Logger) InfoContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.logContext(ctx, INFO, msg, keyvals...)
+}
+
+// WarnContext prints warning log with the fields extracted from ctx and the given key/value pairs.
+func (l *Logger) WarnContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.logContext(ctx, WARN, msg, keyvals...)
+}
+
+// ErrorContext prints error log with the fields extracted from ctx and the given key/value pairs.
+func (l *Logger) ErrorContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.logContext(ctx, ERROR, msg, keyvals...)
+}
+
+// FatalContext prints fatal log with the fields extracted from ctx and the given key/value pairs and exit current process.
+func (l *Logger) FatalContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.logContext(ctx, FATAL, msg, keyvals...)
+}
+
+// These functions write to the logger carried by ctx.
+
+// LogContext prints log at the given level to the logger of ctx.
+func LogContext(ctx context.Context, level Level, msg string, keyvals ...interface{}) {
+	FromContext(ctx).logContext(ctx, level, msg, keyvals...)
+}
+
+// TraceContext prints trace log to the logger of ctx.
+func TraceContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	FromContext(ctx).logContext(ctx, TRACE, msg, keyvals...)
+}
+
+// DebugContext prints debug log to the logger of ctx.
+func DebugContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	FromContext(ctx).logContext(ctx, DEBUG, msg, keyvals...)
+}
+
+// InfoContext prints info log to the logger of ctx.
+func InfoContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	FromContext(ctx).logContext(ctx, INFO, msg, keyvals...)
+}
+
+// WarnContext prints warning log to the logger of ctx.
+func WarnContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	FromContext(ctx).logContext(ctx, WARN, msg, keyvals...)
+}
+
+// ErrorContext prints error log to the logger of ctx.
+func ErrorContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	FromContext(ctx).logContext(ctx, ERROR, msg, keyvals...)
+}
+
+// FatalContext prints fatal log to the logger of ctx and exit current process.
+func FatalContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	FromContext(ctx).logContext(ctx, FATAL, msg, keyvals...)
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+type traceIDKey struct{}
+
+func TestContextLogger(t *testing.T) {
+	unregister := RegisterContextExtractor(func(ctx context.Context) []Field {
+		if id, ok := ctx.Value(traceIDKey{}).(string); ok {
+			return []Field{{Key: "trace", Value: id}}
+		}
+		return nil
+	})
+	t.Cleanup(unregister)
+
+	var b bytes.Buffer
+	l := New(&b, "", Lshortfile)
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "abc")
+	ctx = NewContext(ctx, l.With("request", 1))
+
+	if FromContext(context.Background()) != l {
+		t.Error("FromContext without logger must return the std logger")
+	}
+	InfoContext(ctx, "handled", "status", 200)
+	l.WarnContext(context.Background(), "plain")
+	want := "[ INFO] context_test.go:32: handled request=1 trace=abc status=200\n" +
+		"[ WARN] context_test.go:33: plain\n"
+	if got := b.String(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestUnregisterContextExtractor(t *testing.T) {
+	unregister := RegisterContextExtractor(func(ctx context.Context) []Field {
+		return []Field{{Key: "extracted", Value: true}}
+	})
+	if fields := contextFields(context.Background()); len(fields) != 1 {
+		t.Fatalf("got fields %v", fields)
+	}
+	unregister()
+	unregister()
+	if fields := contextFields(context.Background()); len(fields) != 0 {
+		t.Errorf("got fields %v after unregister", fields)
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin freebsd dragonfly
+
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+// diskFree returns the space of the file system of path available to the process
+func diskFree(path string) (ByteSize, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, &os.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	return ByteSize(uint64(st.Bavail) * uint64(st.Bsize)), nil
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux,!darwin,!freebsd,!dragonfly,!windows
+
+package log
+
+import (
+	"errors"
+	"os"
+)
+
+// diskFree is not supported on this platform
+func diskFree(path string) (ByteSize, error) {
+	return 0, &os.PathError{Op: "statfs", Path: path, Err: errors.New("not supported")}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// operations of a backend reported in BackendError
+const (
+	OpWrite   = "write"
+	OpRotate  = "rotate"
+	OpArchive = "archive"
+	OpRetain  = "retain"
+	OpClose   = "close"
+)
+
+// BackendError is passed to the error handler when a backend fails
+type BackendError struct {
+	Backend Backend // the failing backend
+	Op      string  // the failing operation: write, rotate, archive, retain or close
+	Err     error
+}
+
+// Error implements the error interface
+func (e *BackendError) Error() string {
+	return fmt.Sprintf("log: %s %T: %v", e.Op, e.Backend, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+// writeFailures counts the failed writes of a backend, it is the first
+// field of the backends to keep the counter 64-bit aligned
+type writeFailures struct {
+	failed uint64
+}
+
+// FailedWrites returns the number of writes failed in current backend
+func (c *writeFailures) FailedWrites() uint64 {
+	return atomic.LoadUint64(&c.failed)
+}
+
+func (c *writeFailures) addFailure() {
+	atomic.AddUint64(&c.failed, 1)
+}
+
+var (
+	failedWrites uint64       // number of records failed to write
+	errorHandler atomic.Value // holds the default error handler func(error)
+)
+
+// stderrHandler is the default error handler writing the errors to stderr
+func stderrHandler(err error) {
+	_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+}
+
+// FailedWrites returns the number of writes failed in all backends
+func FailedWrites() uint64 {
+	return atomic.LoadUint64(&failedWrites)
+}
+
+// BackendFailedWrites returns the number of writes failed in backend, the
+// writes of the sinks of a MultiBackend are summed
+func BackendFailedWrites(backend Backend) uint64 {
+	if c, ok := backend.(interface{ FailedWrites() uint64 }); ok {
+		return c.FailedWrites()
+	}
+	return 0
+}
+
+// SetErrorHandler sets the default error handler used by the loggers
+// without their own error handler and for the errors not caused by a
+// record, such as the first rotation. nil writes the errors to stderr.
+// The handler may be called with a logger locked, it must not log to the
+// logger whose error it receives.
+func SetErrorHandler(handler func(error)) {
+	if handler == nil {
+		handler = stderrHandler
+	}
+	errorHandler.Store(handler)
+}
+
+// SetErrorHandler sets the handler receiving the errors of the hooks and
+// backends writing the records of the logger, nil selects the default
+// error handler. The handler is shared by the children created by With.
+// It may be called with the logger locked, such as for the errors of the
+// hooks and of a SyncLog backend, so it must not log to the same logger.
+func (l *Logger) SetErrorHandler(handler func(error)) {
+	root := l.base()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	root.onError = handler
+}
+
+// handleError passes err to the error handler of r, the default error handler if r is nil
+func handleError(r *Record, err error) {
+	if r != nil && r.onError != nil {
+		r.onError(err)
+		return
+	}
+	if handler, ok := errorHandler.Load().(func(error)); ok {
+		handler(err)
+		return
+	}
+	stderrHandler(err)
+}
+
+// backendError reports a failed operation of backend caused by r which may be nil
+func backendError(r *Record, backend Backend, op string, err error) {
+	if op == OpWrite {
+		atomic.AddUint64(&failedWrites, 1)
+		if c, ok := backend.(interface{ addFailure() }); ok {
+			c.addFailure()
+		}
+	}
+	handleError(r, &BackendError{Backend: backend, Op: op, Err: err})
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var errBroken = errors.New("broken")
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errBroken
+}
+
+type failingHook struct{}
+
+func (failingHook) Levels() []Level {
+	return []Level{ERROR}
+}
+
+func (failingHook) Fire(*Record) error {
+	return errBroken
+}
+
+func TestErrorHandler(t *testing.T) {
+	var defaults []error
+	SetErrorHandler(func(err error) { defaults = append(defaults, err) })
+	defer SetErrorHandler(nil)
+
+	l := New(failingWriter{}, "", 0)
+	failed := FailedWrites()
+	l.Info("default handler")
+	if len(defaults) != 1 {
+		t.Fatalf("default handler got %v", defaults)
+	}
+
+	var errs []error
+	l.SetErrorHandler(func(err error) { errs = append(errs, err) })
+	l.AddHook(failingHook{})
+	l.With("k", "v").Error("logger handler")
+
+	if len(defaults) != 1 || len(errs) != 2 {
+		t.Fatalf("default handler got %v, logger handler got %v", defaults, errs)
+	}
+	if !errors.Is(errs[0], errBroken) {
+		t.Errorf("hook error %v does not wrap %v", errs[0], errBroken)
+	}
+	var be *BackendError
+	if !errors.As(errs[1], &be) || be.Op != OpWrite || be.Backend != l.backend || be.Err != errBroken {
+		t.Errorf("got %#v; want write error of %T", errs[1], l.backend)
+	}
+	if n := FailedWrites() - failed; n != 2 {
+		t.Errorf("failed writes %d; want 2", n)
+	}
+}
+
+func TestBackendFailedWrites(t *testing.T) {
+	SetErrorHandler(func(error) {})
+	defer SetErrorHandler(nil)
+
+	broken := NewSyncBackend(failingWriter{})
+	working := NewSyncBackend(&bytes.Buffer{})
+	l := NewMultiLogger("", 0, Sink{Backend: broken}, Sink{Backend: working})
+	l.Info("a")
+	l.Info("b")
+	if n := BackendFailedWrites(broken); n != 2 {
+		t.Errorf("broken backend failed writes %d; want 2", n)
+	}
+	if n := BackendFailedWrites(working); n != 0 {
+		t.Errorf("working backend failed writes %d; want 0", n)
+	}
+	if n := BackendFailedWrites(l.backend); n != 2 {
+		t.Errorf("multi backend failed writes %d; want 2", n)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultFatalTimeout is the default time a FATAL record waits for the backend to flush
+const defaultFatalTimeout = 5 * time.Second
+
+var (
+	exitMu       sync.Mutex
+	exitFunc     = os.Exit
+	exitHooks    []func()
+	fatalTimeout = int64(defaultFatalTimeout) // accessed atomically
+)
+
+// SetExitFunc sets the function called with exit code 1 after a FATAL
+// record is written, nil restores os.Exit. Tests can replace it to check
+// the fatal paths without exiting.
+func SetExitFunc(exit func(code int)) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	if exit == nil {
+		exit = os.Exit
+	}
+	exitFunc = exit
+}
+
+// RegisterExitHook registers a function called after a FATAL record is
+// written and flushed, before the process exits. The hooks are called in
+// the order they are registered.
+func RegisterExitHook(hook func()) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitHooks = append(exitHooks, hook)
+}
+
+// SetFatalTimeout sets the time a FATAL or PANIC record waits for the
+// backend to flush it and the records logged before it, 5 seconds by default
+func SetFatalTimeout(d time.Duration) {
+	atomic.StoreInt64(&fatalTimeout, int64(d))
+}
+
+// flushPending flushes the backend of l within the fatal timeout before
+// the process exits or panics, the caller must not hold the lock of l
+func (l *Logger) flushPending(before string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(atomic.LoadInt64(&fatalTimeout)))
+	defer cancel()
+	if err := l.FlushContext(ctx); err != nil {
+		l.lock()
+		onError := l.base().onError
+		l.unlock()
+		handleError(&Record{onError: onError}, fmt.Errorf("log: flush before %s: %w", before, err))
+	}
+}
+
+// fatal flushes the backend of l, runs the exit hooks and calls the exit
+// function, it is called after a FATAL record without the lock of l
+func (l *Logger) fatal() {
+	l.flushPending("exit")
+
+	exitMu.Lock()
+	hooks := exitHooks
+	exit := exitFunc
+	exitMu.Unlock()
+	for _, hook := range hooks {
+		runExitHook(hook)
+	}
+	exit(1)
+}
+
+// runExitHook calls hook, a panicking hook does not stop the exit
+func runExitHook(hook func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			handleError(nil, fmt.Errorf("log: exit hook panic: %v", err))
+		}
+	}()
+	hook()
+}
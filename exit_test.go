@@ -0,0 +1,46 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFatalExit(t *testing.T) {
+	w := &countingWriter{}
+	l := newAsyncTestLogger(t, w, AsyncOptions{SummaryInterval: -1})
+
+	var events []string
+	defer func() {
+		exitMu.Lock()
+		exitHooks = nil
+		exitMu.Unlock()
+	}()
+	RegisterExitHook(func() { events = append(events, "hook") })
+	RegisterExitHook(func() { panic("broken hook") })
+	SetErrorHandler(func(error) {})
+	defer SetErrorHandler(nil)
+	SetExitFunc(func(code int) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		written := strings.Join(w.writes, "")
+		events = append(events, "exit", strings.Replace(written, "\n", " ", -1))
+		if code != 1 {
+			t.Errorf("exit code %d; want 1", code)
+		}
+	})
+	defer SetExitFunc(nil)
+
+	for i := 0; i < 3; i++ {
+		l.Info(i)
+	}
+	l.Fatal("fatal")
+
+	want := []string{"hook", "exit", "[ INFO] 0 [ INFO] 1 [ INFO] 2 [FATAL] fatal "}
+	if strings.Join(events, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q; want %q", events, want)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+)
+
+// badKey is used for values in a key/value list without a string key
+const badKey = "!BADKEY"
+
+// Field is a key/value pair attached to a log record
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// makeFields converts alternating keys and values into fields, a Field
+// in keyvals is taken as is, a value without a string key is stored under
+// the key !BADKEY
+func makeFields(keyvals []interface{}) []Field {
+	if len(keyvals) == 0 {
+		return nil
+	}
+	fields := make([]Field, 0, (len(keyvals)+1)/2)
+	for i := 0; i < len(keyvals); i++ {
+		switch key := keyvals[i].(type) {
+		case Field:
+			fields = append(fields, key)
+		case string:
+			if i+1 < len(keyvals) {
+				fields = append(fields, Field{Key: key, Value: keyvals[i+1]})
+				i++
+			} else {
+				fields = append(fields, Field{Key: badKey, Value: key})
+			}
+		default:
+			fields = append(fields, Field{Key: badKey, Value: key})
+		}
+	}
+	return fields
+}
+
+// joinFields returns a followed by b, the result never shares its backing array with a
+func joinFields(a, b []Field) []Field {
+	if len(b) == 0 {
+		return a
+	}
+	if len(a) == 0 {
+		return b
+	}
+	return append(a[:len(a):len(a)], b...)
+}
+
+// nilString is written for nil pointer values, as fmt does
+const nilString = "<nil>"
+
+// valueString returns the text representation of a field value
+func valueString(v interface{}) string {
+	if isNilPointer(v) {
+		// the methods of a nil pointer may panic
+		return nilString
+	}
+	switch v := v.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isNilPointer reports whether v is a nil pointer of any type
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// needsQuote reports whether s must be quoted to be read back as a single value
+func needsQuote(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, c := range s {
+		if c <= ' ' || c == '=' || c == '"' || c == utf8.RuneError || !strconv.IsPrint(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendFields writes fields as space separated key=value pairs, the
+// fields of a []Field value are written with the key as prefix
+func appendFields(buf *[]byte, fields []Field) {
+	appendGroup(buf, "", fields)
+}
+
+func appendGroup(buf *[]byte, group string, fields []Field) {
+	for _, field := range fields {
+		if nested, ok := field.Value.([]Field); ok {
+			appendGroup(buf, group+field.Key+".", nested)
+			continue
+		}
+		*buf = append(*buf, ' ')
+		appendKey(buf, group+field.Key)
+		*buf = append(*buf, '=')
+		appendValue(buf, valueString(field.Value))
+	}
+}
+
+// appendKey writes key replacing the characters not allowed in a key by '_'
+func appendKey(buf *[]byte, key string) {
+	if len(key) == 0 {
+		*buf = append(*buf, '_')
+		return
+	}
+	for _, c := range key {
+		if c <= ' ' || c == '=' || c == '"' || c == utf8.RuneError || !strconv.IsPrint(c) {
+			*buf = append(*buf, '_')
+		} else {
+			*buf = append(*buf, string(c)...)
+		}
+	}
+}
+
+// appendValue writes value, quoted if needed so it never spans lines
+func appendValue(buf *[]byte, value string) {
+	if needsQuote(value) {
+		*buf = strconv.AppendQuote(*buf, value)
+	} else {
+		*buf = append(*buf, value...)
+	}
+}
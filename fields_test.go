@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestLoggerWith(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	child := l.With("request", 42, "user", "john doe")
+	child.Infow("done", "latency", "3ms", "err", errors.New("boom"))
+	want := "[ INFO] done request=42 user=\"john doe\" latency=3ms err=boom\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	b.Reset()
+	l.Infoln("no fields")
+	if want := "[ INFO] no fields\n"; b.String() != want {
+		t.Errorf("parent got fields: %q", b.String())
+	}
+}
+
+func TestLoggerWithShared(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Lsequence)
+	child := l.With("k", "v")
+	l.Print("a")
+	child.Print("b")
+	child.SetLogLevel(WARN)
+	if l.Level() != WARN {
+		t.Errorf("child level not shared, parent level is %v", l.Level())
+	}
+	l.Info("dropped")
+	want := "[0000000000] a\n[0000000001] b k=v\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	var out bytes.Buffer
+	l.SetOutput(&out)
+	child.Print("c")
+	if got := out.String(); got != "[0000000002] c k=v\n" {
+		t.Errorf("child output not shared, got %q", got)
+	}
+}
+
+func TestMakeFields(t *testing.T) {
+	fields := makeFields([]interface{}{"a", 1, Field{Key: "b", Value: 2}, 3, "c"})
+	want := []Field{{"a", 1}, {"b", 2}, {badKey, 3}, {badKey, "c"}}
+	if len(fields) != len(want) {
+		t.Fatalf("got %v; want %v", fields, want)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Errorf("field %d: got %v; want %v", i, fields[i], want[i])
+		}
+	}
+}
+
+type nilError struct{ msg string }
+
+func (e *nilError) Error() string { return e.msg }
+
+type nilStringer struct{ s string }
+
+func (s *nilStringer) String() string { return s.s }
+
+func TestNilFieldValues(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	var err *nilError
+	var s *nilStringer
+	l.Infow("nil", "err", err, "stringer", s)
+	if want := "[ INFO] nil err=<nil> stringer=<nil>\n"; b.String() != want {
+		t.Errorf("got %q; want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"sync"
+)
+
+// Formatter formats a log record into its output representation
+type Formatter interface {
+	// Format appends the formatted record to buf and returns the extended buffer,
+	// the output of a record must end with a newline
+	Format(r *Record, buf []byte) []byte
+}
+
+// TextFormatter is the default formatter writing the header selected by
+// the Lxxx flags followed by the message and its key=value fields
+type TextFormatter struct{}
+
+// Format implements the Formatter interface
+func (f *TextFormatter) Format(r *Record, buf []byte) []byte {
+	r.formatHeader(&buf)
+	buf = append(buf, r.Message()...)
+	appendFields(&buf, r.fields)
+	appendStack(&buf, r.stack)
+	return append(buf, '\n')
+}
+
+// appendStack writes each frame of stack on its own indented line
+func appendStack(buf *[]byte, stack []string) {
+	for _, frame := range stack {
+		*buf = append(*buf, "\n\t"...)
+		*buf = append(*buf, frame...)
+	}
+}
+
+var (
+	defaultFormatter Formatter = &TextFormatter{}
+
+	// bufPool holds the buffers used for formatting records
+	bufPool = sync.Pool{
+		New: func() interface{} {
+			buf := make([]byte, 0, 256)
+			return &buf
+		},
+	}
+)
+
+// maxPooledBuffer limits the size of a buffer put back to bufPool
+const maxPooledBuffer = 64 * 1024
+
+func getBuffer() *[]byte {
+	buf := bufPool.Get().(*[]byte)
+	*buf = (*buf)[:0]
+	return buf
+}
+
+func putBuffer(buf *[]byte) {
+	if cap(*buf) <= maxPooledBuffer {
+		bufPool.Put(buf)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+type levelFormatter struct {
+	tag string
+}
+
+func (f *levelFormatter) Format(r *Record, buf []byte) []byte {
+	buf = append(buf, f.tag...)
+	buf = strconv.AppendUint(buf, r.Index(), 10)
+	buf = append(buf, ' ')
+	buf = append(buf, r.Message()...)
+	return append(buf, '\n')
+}
+
+func TestLoggerFormatter(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Ldate|Ltime)
+	// New replaces the std logger, restore the default formatters for other tests
+	defer l.SetFormatter(nil)
+	defer l.backend.SetFormatter(nil)
+	l.SetFormatter(&levelFormatter{tag: "logger-"})
+	l.Println("hello")
+	l.backend.SetFormatter(&levelFormatter{tag: "backend-"})
+	l.Println("world")
+	want := "logger-0 hello\nbackend-1 world\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestRecordAccessors(t *testing.T) {
+	var r *Record
+	l := New(&bytes.Buffer{}, "pre:", Lshortfile)
+	l.SetName("app")
+	defer l.SetFormatter(nil)
+	l.SetFormatter(formatterFunc(func(rec *Record, buf []byte) []byte {
+		r = rec
+		return buf
+	}))
+	l.Warnf("value %d", 7)
+	if r == nil {
+		t.Fatal("formatter not called")
+	}
+	if r.Level() != WARN || r.Prefix() != "pre:" || r.Name() != "app" || r.Message() != "value 7" {
+		t.Errorf("unexpected record: %v %q %q %q", r.Level(), r.Prefix(), r.Name(), r.Message())
+	}
+	if r.Line() == 0 || r.File() == "" || r.Function() == "" || r.Time().IsZero() {
+		t.Errorf("missing caller info: %s:%d %s", r.File(), r.Line(), r.Function())
+	}
+}
+
+type formatterFunc func(r *Record, buf []byte) []byte
+
+func (f formatterFunc) Format(r *Record, buf []byte) []byte {
+	return f(r, buf)
+}
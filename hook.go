@@ -0,0 +1,70 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"fmt"
+)
+
+// Hook is called for each record of its levels before the record is
+// handed to the backend. Fire is called with the logger locked, it may
+// add fields to the record but must not log to the same logger.
+type Hook interface {
+	// Levels returns the levels of the records the hook fires for,
+	// nil fires for all records including those of Print[f|ln]
+	Levels() []Level
+
+	// Fire is called for each record of the levels
+	Fire(r *Record) error
+}
+
+// levelHooks is an immutable index of hooks by level, it is replaced as a
+// whole when a hook is added
+type levelHooks struct {
+	all    []Hook           // hooks of all levels
+	levels map[Level][]Hook // hooks of the given levels
+}
+
+// fire calls the hooks of the level of r, the errors of the hooks are
+// reported instead of stopping the record
+func (h *levelHooks) fire(r *Record) {
+	for _, hook := range h.all {
+		if err := hook.Fire(r); err != nil {
+			handleError(r, fmt.Errorf("log: hook %T: %w", hook, err))
+		}
+	}
+	for _, hook := range h.levels[r.level] {
+		if err := hook.Fire(r); err != nil {
+			handleError(r, fmt.Errorf("log: hook %T: %w", hook, err))
+		}
+	}
+}
+
+// AddHook adds a hook to the logger, hooks are shared by the children created by With
+func (l *Logger) AddHook(hook Hook) {
+	root := l.base()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	hooks := &levelHooks{levels: make(map[Level][]Hook)}
+	if root.hooks != nil {
+		hooks.all = append(hooks.all, root.hooks.all...)
+		for level, current := range root.hooks.levels {
+			hooks.levels[level] = append([]Hook(nil), current...)
+		}
+	}
+	levels := hook.Levels()
+	if levels == nil {
+		hooks.all = append(hooks.all, hook)
+	}
+	for _, level := range levels {
+		hooks.levels[level] = append(hooks.levels[level], hook)
+	}
+	root.hooks = hooks
+}
+
+// AddHook adds a hook to the std logger
+func AddHook(hook Hook) {
+	std().AddHook(hook)
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+type testHook struct {
+	levels []Level
+	fired  []string
+}
+
+func (h *testHook) Levels() []Level {
+	return h.levels
+}
+
+func (h *testHook) Fire(r *Record) error {
+	h.fired = append(h.fired, r.Message())
+	r.AddFields("build", "v1")
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	errors := &testHook{levels: []Level{ERROR, FATAL}}
+	all := &testHook{}
+	l.AddHook(errors)
+	l.With("k", 1).AddHook(all)
+
+	l.Info("info")
+	l.With("k", 2).Error("error")
+	l.Print("print")
+
+	if len(errors.fired) != 1 || errors.fired[0] != "error" {
+		t.Errorf("error hook fired for %q", errors.fired)
+	}
+	if len(all.fired) != 3 {
+		t.Errorf("hook of all levels fired for %q", all.fired)
+	}
+	want := "[ INFO] info build=v1\n[ERROR] error k=2 build=v1 build=v1\nprint build=v1\n"
+	if b.String() != want {
+		t.Errorf("got %q; want %q", b.String(), want)
+	}
+}
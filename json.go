@@ -0,0 +1,317 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// default keys of JSONFormatter
+const (
+	JSONTimeKey      = "ts"
+	JSONSequenceKey  = "seq"
+	JSONLevelKey     = "level"
+	JSONNameKey      = "logger"
+	JSONGoroutineKey = "goroutine"
+	JSONCallerKey    = "caller"
+	JSONFunctionKey  = "func"
+	JSONMessageKey   = "msg"
+	JSONStackKey     = "stack"
+)
+
+// JSONFieldPrefix is prepended to the key of a field clashing with one of
+// the keys of JSONFormatter or LogfmtFormatter: a field msg is written as
+// fields.msg
+const JSONFieldPrefix = "fields."
+
+// JSONFormatter writes each record as one JSON object per line. The Lxxx
+// flags of the logger select the keys written besides the message:
+//   - Ldate, Ltime or Lmicroseconds: ts
+//   - Lsequence: seq
+//   - level of Debug/Info/Warn/Error/Fatal: level
+//   - Lloggername: logger
+//   - Lgoroutineid: goroutine
+//   - Lshortfile or Llongfile: caller
+//   - Lshortfunc or Llongfunc: func
+//
+// followed by the fields of the record and the stack array of the records
+// of Panic[f|ln] or, with Lstacktrace, of the records at the stack level.
+// Empty key names select the defaults. A field whose key clashes with one
+// of these keys is written with JSONFieldPrefix. The records are never
+// colored.
+type JSONFormatter struct {
+	TimeKey      string
+	SequenceKey  string
+	LevelKey     string
+	NameKey      string
+	GoroutineKey string
+	CallerKey    string
+	FunctionKey  string
+	MessageKey   string
+	StackKey     string
+
+	// TimeFormat is the format of the ts value, if empty the format of the
+	// logger is used or TimeRFC3339Nano for TimeDefault. Epoch formats are
+	// written as numbers.
+	TimeFormat TimeFormat
+}
+
+// recordTimeFormat returns format if not empty or the time format of r
+func recordTimeFormat(format TimeFormat, r *Record) TimeFormat {
+	if format != TimeDefault {
+		return format
+	}
+	return r.timeFormat
+}
+
+func keyOr(key, def string) string {
+	if len(key) > 0 {
+		return key
+	}
+	return def
+}
+
+// Format implements the Formatter interface
+func (f *JSONFormatter) Format(r *Record, buf []byte) []byte {
+	buf = append(buf, '{')
+	first := true
+	key := func(k string) {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = appendJSONString(buf, k)
+		buf = append(buf, ':')
+	}
+
+	if r.flag&(Ldate|Ltime|Lmicroseconds) != 0 && !r.time.IsZero() {
+		key(keyOr(f.TimeKey, JSONTimeKey))
+		format := recordTimeFormat(f.TimeFormat, r)
+		if format.isEpoch() {
+			buf = appendTime(buf, r.time, format)
+		} else {
+			buf = append(buf, '"')
+			buf = appendTime(buf, r.time, format)
+			buf = append(buf, '"')
+		}
+	}
+	if r.flag&Lsequence != 0 {
+		key(keyOr(f.SequenceKey, JSONSequenceKey))
+		buf = strconv.AppendUint(buf, r.index, 10)
+	}
+	if r.level > none {
+		key(keyOr(f.LevelKey, JSONLevelKey))
+		buf = appendJSONString(buf, levelString(r.level))
+	}
+	if r.flag&Lloggername != 0 && len(r.Name()) > 0 {
+		key(keyOr(f.NameKey, JSONNameKey))
+		buf = appendJSONString(buf, r.Name())
+	}
+	if r.flag&Lgoroutineid != 0 {
+		key(keyOr(f.GoroutineKey, JSONGoroutineKey))
+		buf = append(buf, id()...)
+	}
+	if r.flag&(Lshortfile|Llongfile) != 0 {
+		file := r.File()
+		if r.flag&Lshortfile != 0 {
+			file = splitLast(file, '/')
+		}
+		key(keyOr(f.CallerKey, JSONCallerKey))
+		buf = appendJSONString(buf, file+":"+strconv.Itoa(r.line))
+	}
+	if r.flag&(Lshortfunc|Llongfunc) != 0 {
+		function := r.Function()
+		if r.flag&Lshortfunc != 0 {
+			function = splitLast(function, '.')
+		}
+		key(keyOr(f.FunctionKey, JSONFunctionKey))
+		buf = appendJSONString(buf, function)
+	}
+	key(keyOr(f.MessageKey, JSONMessageKey))
+	buf = appendJSONString(buf, r.Message())
+
+	for _, field := range r.fields {
+		if f.reserved(field.Key) {
+			key(JSONFieldPrefix + field.Key)
+		} else {
+			key(field.Key)
+		}
+		buf = appendJSONValue(buf, field.Value)
+	}
+	if len(r.stack) > 0 {
+		key(keyOr(f.StackKey, JSONStackKey))
+		buf = append(buf, '[')
+		for i, frame := range r.stack {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSONString(buf, frame)
+		}
+		buf = append(buf, ']')
+	}
+	return append(buf, '}', '\n')
+}
+
+// reserved reports whether k is one of the keys written by f besides the fields
+func (f *JSONFormatter) reserved(k string) bool {
+	for _, key := range [...]string{
+		keyOr(f.TimeKey, JSONTimeKey),
+		keyOr(f.SequenceKey, JSONSequenceKey),
+		keyOr(f.LevelKey, JSONLevelKey),
+		keyOr(f.NameKey, JSONNameKey),
+		keyOr(f.GoroutineKey, JSONGoroutineKey),
+		keyOr(f.CallerKey, JSONCallerKey),
+		keyOr(f.FunctionKey, JSONFunctionKey),
+		keyOr(f.MessageKey, JSONMessageKey),
+		keyOr(f.StackKey, JSONStackKey),
+	} {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// appendJSONValue appends v encoded as JSON, a []Field value is written as
+// a nested object and a nil pointer as null
+func appendJSONValue(buf []byte, v interface{}) []byte {
+	if isNilPointer(v) {
+		// the methods of a nil pointer may panic
+		return append(buf, "null"...)
+	}
+	switch v := v.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return appendJSONString(buf, v)
+	case bool:
+		return strconv.AppendBool(buf, v)
+	case int:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int8:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int16:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int32:
+		return strconv.AppendInt(buf, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(buf, v, 10)
+	case uint:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint8:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint16:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint32:
+		return strconv.AppendUint(buf, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(buf, v, 10)
+	case float32:
+		return appendJSONFloat(buf, float64(v), 32)
+	case float64:
+		return appendJSONFloat(buf, v, 64)
+	case time.Duration:
+		return appendJSONString(buf, v.String())
+	case []Field:
+		buf = append(buf, '{')
+		for i, field := range v {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendJSONString(buf, field.Key)
+			buf = append(buf, ':')
+			buf = appendJSONValue(buf, field.Value)
+		}
+		return append(buf, '}')
+	case json.Marshaler:
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return appendJSONString(buf, valueString(v))
+		}
+		// an indented value would break the one object per line output
+		compacted := bytes.NewBuffer(buf)
+		if err := json.Compact(compacted, data); err != nil {
+			return appendJSONString(buf, valueString(v))
+		}
+		return compacted.Bytes()
+	case error:
+		return appendJSONString(buf, v.Error())
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return appendJSONString(buf, valueString(v))
+		}
+		return append(buf, data...)
+	}
+}
+
+// appendJSONFloat appends f as JSON number, NaN and infinities are written as strings
+func appendJSONFloat(buf []byte, f float64, bits int) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(buf, `"NaN"`...)
+	case math.IsInf(f, 1):
+		return append(buf, `"+Inf"`...)
+	case math.IsInf(f, -1):
+		return append(buf, `"-Inf"`...)
+	}
+	return strconv.AppendFloat(buf, f, 'g', -1, bits)
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONString appends s as a quoted JSON string, invalid UTF-8 is replaced by U+FFFD
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are line terminators in javascript
+		if c == '\u2028' || c == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hex[c&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
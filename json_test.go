@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestJSONFormatter(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Ldate|Ltime|Lsequence|Lshortfile|Lshortfunc|Lloggername)
+	defer l.SetFormatter(nil)
+	l.SetName("app")
+	l.SetFormatter(&JSONFormatter{MessageKey: "message", TimeFormat: time.RFC3339})
+	l.With("user", "j\"o\nhn", "nested", []Field{{"a", 1}}).Errorw("failed\tbadly", "latency", 3*time.Millisecond, "ok", false)
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &m); err != nil {
+		t.Fatalf("invalid json %q: %v", b.String(), err)
+	}
+	want := map[string]interface{}{
+		"seq":     float64(0),
+		"level":   "ERROR",
+		"logger":  "app",
+		"func":    "TestJSONFormatter",
+		"message": "failed\tbadly",
+		"user":    "j\"o\nhn",
+		"latency": "3ms",
+		"ok":      false,
+	}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("%s: got %v; want %v", k, m[k], v)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, m["ts"].(string)); err != nil {
+		t.Errorf("ts: %v", err)
+	}
+	if nested, ok := m["nested"].(map[string]interface{}); !ok || nested["a"] != float64(1) {
+		t.Errorf("nested: got %v", m["nested"])
+	}
+	if bytes.Count(b.Bytes(), []byte("\n")) != 1 {
+		t.Errorf("expected a single line, got %q", b.String())
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"", "plain", "quote\"back\\slash", "ctrl\x00\x1f", "bad\xffutf8", "line\u2028sep", "日本語"} {
+		var got string
+		data := appendJSONString(nil, s)
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Errorf("%q: invalid json %s: %v", s, data, err)
+			continue
+		}
+		want := string(bytes.ToValidUTF8([]byte(s), []byte("\ufffd")))
+		if got != want {
+			t.Errorf("got %q; want %q", got, want)
+		}
+	}
+}
+
+func TestJSONReservedKeys(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Lcolor)
+	l.SetFormatter(&JSONFormatter{LevelKey: "severity"})
+	l.With("msg", "field", "level", 1).Info("message")
+	want := `{"severity":"INFO","msg":"message","fields.msg":"field","level":1}` + "\n"
+	if b.String() != want {
+		t.Errorf("got %q; want %q", b.String(), want)
+	}
+
+	backend := NewSyncBackend(&b)
+	r := &Record{flag: Lcolor, formatter: &JSONFormatter{}}
+	if r.colored(backend) {
+		t.Error("JSON record colored")
+	}
+	if r.formatter = nil; !r.colored(backend) {
+		t.Error("text record not colored")
+	}
+}
+
+// indentedValue marshals itself as indented JSON
+type indentedValue struct{}
+
+func (indentedValue) MarshalJSON() ([]byte, error) {
+	return []byte("{\n  \"a\": [\n    1,\n    2\n  ]\n}"), nil
+}
+
+func TestJSONValues(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	l.SetFormatter(&JSONFormatter{})
+	var tm *time.Time
+	var err *nilError
+	l.Infow("values", "time", tm, "err", err, "indented", indentedValue{})
+	want := `{"level":"INFO","msg":"values","time":null,"err":null,"indented":{"a":[1,2]}}` + "\n"
+	if b.String() != want {
+		t.Errorf("got  %q\nwant %q", b.String(), want)
+	}
+}
@@ -0,0 +1,382 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultLayoutTime is the time layout of %{time} without argument
+const defaultLayoutTime = "2006/01/02 15:04:05.000000"
+
+// emitter appends one part of a layout to buf
+type emitter func(r *Record, buf []byte) []byte
+
+// LayoutFormatter formats records by a layout template compiled by
+// NewLayoutFormatter. A layout is literal text with the following verbs:
+//
+//	%{prefix}        logger prefix
+//	%{time}          time of the record in the time format of the logger, %{time:format}
+//	                 formats by one of rfc3339, rfc3339nano, iso8601, unix, unixmilli,
+//	                 unixnano or a time.Format layout
+//	%{seq}           sequence number
+//	%{level}         level name
+//	%{name}          logger name
+//	%{goroutine}     current goroutine id
+//	%{file}          final file name element of the caller
+//	%{longfile}      full file name of the caller
+//	%{line}          line number of the caller
+//	%{caller}        final file name element and line number: d.go:23
+//	%{func}          short function name of the caller: printf
+//	%{longfunc}      full function name of the caller
+//	%{msg}           the message
+//	%{fields}        the fields as key=value pairs, each preceded by a space
+//	%%               a literal %
+//
+// All verbs except time take an optional width: %{level:5} right aligns
+// the level to 5 characters, %{level:-5} left aligns it and %{seq:010}
+// pads the sequence with zeros. Text between %[ and %] is only written if
+// at least one verb inside it is not empty, e.g. %[[%{level}] %] is
+// omitted for the records of Print[f|ln]. Fields are appended to the line
+// if the layout has no %{fields} verb.
+type LayoutFormatter struct {
+	layout   string
+	emitters []emitter
+}
+
+// NewLayoutFormatter compiles layout into a LayoutFormatter
+func NewLayoutFormatter(layout string) (*LayoutFormatter, error) {
+	emitters, hasFields, err := compileLayout(layout)
+	if err != nil {
+		return nil, err
+	}
+	if !hasFields {
+		emitters = append(emitters, emitFields)
+	}
+	return &LayoutFormatter{layout: layout, emitters: emitters}, nil
+}
+
+// Layout returns the layout the formatter is compiled from
+func (f *LayoutFormatter) Layout() string {
+	return f.layout
+}
+
+// Format implements the Formatter interface
+func (f *LayoutFormatter) Format(r *Record, buf []byte) []byte {
+	for _, emit := range f.emitters {
+		buf = emit(r, buf)
+	}
+	appendStack(&buf, r.stack)
+	return append(buf, '\n')
+}
+
+// FlagsLayout returns the layout producing the same header as the TextFormatter for flag
+func FlagsLayout(flag int) string {
+	var b strings.Builder
+	b.WriteString("%{prefix}")
+	if flag&(Ldate|Ltime|Lmicroseconds) != 0 {
+		var layout []string
+		if flag&Ldate != 0 {
+			layout = append(layout, "2006/01/02")
+		}
+		if flag&Lmicroseconds != 0 {
+			layout = append(layout, "15:04:05.000000")
+		} else if flag&Ltime != 0 {
+			layout = append(layout, "15:04:05")
+		}
+		b.WriteString("%{time:" + strings.Join(layout, " ") + "} ")
+	}
+	if flag&Lsequence != 0 {
+		b.WriteString("[%{seq:010}] ")
+	}
+	b.WriteString("%[[%{level:5}] %]")
+	if flag&Lloggername != 0 {
+		b.WriteString("%[%{name}")
+		if flag&Lgoroutineid != 0 {
+			b.WriteString("-%{goroutine}")
+		}
+		b.WriteString(" %]")
+	}
+	if flag&(Lshortfile|Llongfile) != 0 {
+		if flag&Lshortfile != 0 {
+			b.WriteString("%{file}:%{line}:")
+		} else {
+			b.WriteString("%{longfile}:%{line}:")
+		}
+		if flag&(Lshortfunc|Llongfunc) == 0 {
+			b.WriteString(" ")
+		}
+	}
+	if flag&(Lshortfunc|Llongfunc) != 0 {
+		if flag&Lshortfunc != 0 {
+			b.WriteString("%{func}: ")
+		} else {
+			b.WriteString("%{longfunc}: ")
+		}
+	}
+	b.WriteString("%{msg}%{fields}")
+	return b.String()
+}
+
+// sectionPart is an emitter of a %[ %] section
+type sectionPart struct {
+	emit emitter
+	verb bool
+}
+
+// compileLayout compiles layout into emitters, reporting whether it has a %{fields} verb
+func compileLayout(layout string) ([]emitter, bool, error) {
+	var (
+		emitters  []emitter
+		section   []sectionPart // parts of the open %[ section
+		inSection bool
+		hasFields bool
+		literal   []byte
+	)
+	add := func(e emitter, verb bool) {
+		if inSection {
+			section = append(section, sectionPart{emit: e, verb: verb})
+		} else {
+			emitters = append(emitters, e)
+		}
+	}
+	flush := func() {
+		if len(literal) > 0 {
+			text := string(literal)
+			add(func(r *Record, buf []byte) []byte {
+				return append(buf, text...)
+			}, false)
+			literal = literal[:0]
+		}
+	}
+
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			literal = append(literal, layout[i])
+			continue
+		}
+		if i+1 >= len(layout) {
+			return nil, false, fmt.Errorf("log: layout %q ends with %%", layout)
+		}
+		i++
+		switch layout[i] {
+		case '%':
+			literal = append(literal, '%')
+		case '[':
+			if inSection {
+				return nil, false, fmt.Errorf("log: nested %%[ in layout %q", layout)
+			}
+			flush()
+			inSection = true
+		case ']':
+			if !inSection {
+				return nil, false, fmt.Errorf("log: %%] without %%[ in layout %q", layout)
+			}
+			flush()
+			emitters = append(emitters, sectionEmitter(section))
+			section = nil
+			inSection = false
+		case '{':
+			end := strings.IndexByte(layout[i:], '}')
+			if end < 0 {
+				return nil, false, fmt.Errorf("log: unterminated %%{ in layout %q", layout)
+			}
+			verb := layout[i+1 : i+end]
+			i += end
+			var arg string
+			if n := strings.IndexByte(verb, ':'); n >= 0 {
+				verb, arg = verb[:n], verb[n+1:]
+			}
+			e, err := verbEmitter(verb, arg)
+			if err != nil {
+				return nil, false, err
+			}
+			if verb == "fields" {
+				hasFields = true
+			}
+			flush()
+			add(e, true)
+		default:
+			return nil, false, fmt.Errorf("log: unknown layout directive %%%c in layout %q", layout[i], layout)
+		}
+	}
+	if inSection {
+		return nil, false, fmt.Errorf("log: unterminated %%[ in layout %q", layout)
+	}
+	flush()
+	return emitters, hasFields, nil
+}
+
+// sectionEmitter writes the output of parts only if any of its verbs is not empty
+func sectionEmitter(parts []sectionPart) emitter {
+	return func(r *Record, buf []byte) []byte {
+		start := len(buf)
+		empty := true
+		for _, part := range parts {
+			n := len(buf)
+			buf = part.emit(r, buf)
+			if part.verb && len(buf) > n {
+				empty = false
+			}
+		}
+		if empty {
+			return buf[:start]
+		}
+		return buf
+	}
+}
+
+// stringVerbs are the verbs emitting a string of the record
+var stringVerbs = map[string]func(r *Record) string{
+	"prefix":    (*Record).Prefix,
+	"level":     levelName,
+	"name":      (*Record).Name,
+	"goroutine": func(*Record) string { return id() },
+	"file":      func(r *Record) string { return splitLast(r.File(), '/') },
+	"longfile":  (*Record).File,
+	"func":      func(r *Record) string { return splitLast(r.Function(), '.') },
+	"longfunc":  (*Record).Function,
+	"msg":       (*Record).Message,
+	"caller": func(r *Record) string {
+		if r.File() == "" {
+			return ""
+		}
+		return splitLast(r.File(), '/') + ":" + strconv.Itoa(r.line)
+	},
+}
+
+// levelName returns the name of the record level, empty for Print[f|ln]
+func levelName(r *Record) string {
+	if r.level == none {
+		return ""
+	}
+	return levelString(r.level)
+}
+
+// verbEmitter returns the emitter of %{verb:arg}
+func verbEmitter(verb, arg string) (emitter, error) {
+	switch verb {
+	case "time":
+		format, ok := timeFormatNames[arg]
+		if !ok {
+			format = TimeFormat(arg)
+		}
+		return func(r *Record, buf []byte) []byte {
+			if r.time.IsZero() {
+				return buf
+			}
+			if format != TimeDefault {
+				return appendTime(buf, r.time, format)
+			}
+			if r.timeFormat != TimeDefault {
+				return appendTime(buf, r.time, r.timeFormat)
+			}
+			return r.time.AppendFormat(buf, defaultLayoutTime)
+		}, nil
+	case "fields":
+		if len(arg) > 0 {
+			return nil, fmt.Errorf("log: layout verb %%{fields} takes no argument")
+		}
+		return emitFields, nil
+	}
+
+	width, left, zero, err := parseWidth(arg)
+	if err != nil {
+		return nil, fmt.Errorf("log: bad width of layout verb %%{%s:%s}", verb, arg)
+	}
+
+	var e emitter
+	switch verb {
+	case "seq":
+		e = func(r *Record, buf []byte) []byte {
+			return strconv.AppendUint(buf, r.index, 10)
+		}
+	case "line":
+		e = func(r *Record, buf []byte) []byte {
+			return strconv.AppendInt(buf, int64(r.line), 10)
+		}
+	default:
+		value, ok := stringVerbs[verb]
+		if !ok {
+			return nil, fmt.Errorf("log: unknown layout verb %%{%s}", verb)
+		}
+		e = func(r *Record, buf []byte) []byte {
+			return append(buf, value(r)...)
+		}
+	}
+	if width == 0 {
+		return e, nil
+	}
+	return padEmitter(e, width, left, zero), nil
+}
+
+func emitFields(r *Record, buf []byte) []byte {
+	appendFields(&buf, r.fields)
+	return buf
+}
+
+// parseWidth parses the width argument [-][0]N of a verb
+func parseWidth(arg string) (width int, left, zero bool, err error) {
+	if len(arg) == 0 {
+		return 0, false, false, nil
+	}
+	if arg[0] == '-' {
+		left = true
+		arg = arg[1:]
+	} else if arg[0] == '0' {
+		zero = true
+	}
+	width, err = strconv.Atoi(arg)
+	if err != nil || width < 0 {
+		return 0, false, false, fmt.Errorf("bad width %q", arg)
+	}
+	return width, left, zero, nil
+}
+
+// padEmitter pads the output of e to width characters
+func padEmitter(e emitter, width int, left, zero bool) emitter {
+	pad := byte(' ')
+	if zero {
+		pad = '0'
+	}
+	return func(r *Record, buf []byte) []byte {
+		start := len(buf)
+		buf = e(r, buf)
+		n := width - utf8.RuneCount(buf[start:])
+		if n <= 0 || len(buf) == start {
+			return buf
+		}
+		for i := 0; i < n; i++ {
+			buf = append(buf, pad)
+		}
+		if !left {
+			// move the value behind the padding
+			end := len(buf) - n
+			copy(buf[start+n:], buf[start:end])
+			for i := start; i < start+n; i++ {
+				buf[i] = pad
+			}
+		}
+		return buf
+	}
+}
+
+// SetLayout compiles layout into a LayoutFormatter used as the formatter of the logger
+func (l *Logger) SetLayout(layout string) error {
+	f, err := NewLayoutFormatter(layout)
+	if err != nil {
+		return err
+	}
+	l.SetFormatter(f)
+	return nil
+}
+
+// SetLayout sets the layout of the std logger
+func SetLayout(layout string) error {
+	return std().SetLayout(layout)
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFlagsLayout(t *testing.T) {
+	flags := []int{
+		0, Ldate, Ltime, Lmicroseconds, LstdFlags, Lsequence, Lloggername,
+		Lshortfile, Llongfile, Lshortfunc, Llongfunc, Lshortfile | Lshortfunc,
+		Lfull &^ Lcolor, Ldate | Ltime | Lmicroseconds | Llongfile | Llongfunc | LUTC,
+	}
+	for _, flag := range flags {
+		var records []*Record
+		l := New(&bytes.Buffer{}, "pre:", flag)
+		l.SetName("app")
+		l.SetFormatter(formatterFunc(func(r *Record, buf []byte) []byte {
+			records = append(records, r)
+			return buf
+		}))
+		l.With("k", "v w").Warnln("hello", 23)
+		l.Print("print")
+		l.SetFormatter(nil)
+
+		f, err := NewLayoutFormatter(FlagsLayout(flag))
+		if err != nil {
+			t.Fatalf("flag %x: %v", flag, err)
+		}
+		for _, r := range records {
+			want := string(defaultFormatter.Format(r, nil))
+			if got := string(f.Format(r, nil)); got != want {
+				t.Errorf("flag %x layout %q:\ngot  %q\nwant %q", flag, FlagsLayout(flag), got, want)
+			}
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	defer l.SetFormatter(nil)
+	if err := l.SetLayout("%{level:-5}|%{seq:04}|%{caller}|%[<%{name}>%]%{msg} 100%%"); err != nil {
+		t.Fatal(err)
+	}
+	l.With("k", 1).Info("a")
+	l.SetName("app")
+	l.SetFlags(Lshortfile)
+	l.Error("b")
+	want := "INFO |0000||a 100% k=1\nERROR|0001|layout_test.go:53|<app>b 100%\n"
+	if got := b.String(); got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestLayoutErrors(t *testing.T) {
+	for _, layout := range []string{"%{bogus}", "%{msg", "%[%{msg}", "%]", "%[%[%]%]", "%x", "50%", "%{level:x}", "%{fields:1}"} {
+		if _, err := NewLayoutFormatter(layout); err == nil {
+			t.Errorf("layout %q: expected error", layout)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Level log level
+type Level uint32
+
+// LevelStep is the distance between two predefined levels, levels
+// registered by RegisterLevel can be placed between them.
+//
+// The predefined levels were numbered 1 (FATAL) to 5 (DEBUG) before the
+// gaps for custom levels were added, a level stored as number by an older
+// version is converted by multiplying it by LevelStep. ParseLevel, Set and
+// UnmarshalText still accept the old numbers 1 to 5.
+const LevelStep = 10
+
+// supported levels, a record is written if its level is lower than or equal to the logger level
+const (
+	none Level = iota * LevelStep
+	FATAL
+	ERROR
+	WARN
+	INFO
+	DEBUG
+	TRACE
+)
+
+// PANIC is the level of the records written by Panic[f|ln] and the
+// recover helpers, it is more severe than ERROR and does not exit
+const PANIC = FATAL + LevelStep/2
+
+var (
+	levelsMu sync.RWMutex // protects levels, levelColors and colorSeqs
+	levels   = map[Level]string{
+		none:  "",
+		FATAL: "FATAL",
+		PANIC: "PANIC",
+		ERROR: "ERROR",
+		WARN:  "WARN",
+		INFO:  "INFO",
+		DEBUG: "DEBUG",
+		TRACE: "TRACE",
+	}
+	levelColors = map[Level]Color{
+		none:  ColorWhite,
+		FATAL: ColorMagenta,
+		PANIC: ColorMagenta,
+		ERROR: ColorRed,
+		WARN:  ColorYellow,
+		INFO:  ColorGreen,
+		DEBUG: ColorCyan,
+		TRACE: ColorBlue,
+	}
+	// colorSeqs holds the normal and bold ANSI sequences of the colors in use
+	colorSeqs = map[Color][2]string{}
+)
+
+func init() {
+	for _, col := range levelColors {
+		colorSeqs[col] = [2]string{colorSeq(col), colorSeqBold(col)}
+	}
+}
+
+// RegisterLevel registers a custom level with its name and an optional
+// color, ColorWhite if not given. The value decides which records are
+// written: a NOTICE level registered as WARN+5 is written by a logger at
+// INFO but not by a logger at WARN. Predefined levels can not be replaced
+// and the name must not be used by another level.
+func RegisterLevel(value Level, name string, color ...Color) error {
+	if value == none || value == PANIC || value%LevelStep == 0 && value <= TRACE {
+		return fmt.Errorf("log: level %d is predefined", value)
+	}
+	if value < FATAL {
+		return fmt.Errorf("log: level %d is more severe than FATAL", value)
+	}
+	if len(name) == 0 {
+		return fmt.Errorf("log: empty name of level %d", value)
+	}
+	col := ColorWhite
+	if len(color) > 0 {
+		col = color[0]
+	}
+	if _, ok := levelAliases[strings.ToUpper(name)]; ok {
+		return fmt.Errorf("log: level name %q is in use", name)
+	}
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	for level, levelName := range levels {
+		if level != value && strings.EqualFold(levelName, name) {
+			return fmt.Errorf("log: level name %q is in use", name)
+		}
+	}
+	levels[value] = name
+	levelColors[value] = col
+	if _, ok := colorSeqs[col]; !ok {
+		colorSeqs[col] = [2]string{colorSeq(col), colorSeqBold(col)}
+	}
+	return nil
+}
+
+// levelAliases are alternative names accepted by ParseLevel
+var levelAliases = map[string]Level{
+	"NONE":    none,
+	"OFF":     none,
+	"CRIT":    FATAL,
+	"ERR":     ERROR,
+	"WARNING": WARN,
+}
+
+// ParseLevel parses a level name case-insensitively, the names of levels
+// registered by RegisterLevel, the numeric value of a level and the
+// LEVEL(n) form of Level.String are accepted too. The numbers 1 to 5 are
+// the predefined levels numbered without LevelStep.
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if level, ok := levelAliases[name]; ok {
+		return level, nil
+	}
+	levelsMu.RLock()
+	for level, levelName := range levels {
+		if level != none && strings.EqualFold(levelName, name) {
+			levelsMu.RUnlock()
+			return level, nil
+		}
+	}
+	levelsMu.RUnlock()
+	if strings.HasPrefix(name, "LEVEL(") && strings.HasSuffix(name, ")") {
+		// the String form of an unregistered level
+		if n, err := strconv.ParseUint(name[len("LEVEL("):len(name)-1], 10, 32); err == nil {
+			return Level(n), nil
+		}
+	}
+	if n, err := strconv.ParseUint(name, 10, 32); err == nil {
+		if n > 0 && Level(n) <= DEBUG/LevelStep {
+			// number of a predefined level before LevelStep
+			return Level(n) * LevelStep, nil
+		}
+		return Level(n), nil
+	}
+	return none, fmt.Errorf("log: unknown level %q", s)
+}
+
+// String implements the fmt.Stringer and flag.Value interface
+func (l Level) String() string {
+	if l == none {
+		return "NONE"
+	}
+	return levelString(l)
+}
+
+// Set implements the flag.Value interface
+func (l *Level) Set(s string) error {
+	level, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface
+func (l *Level) UnmarshalText(text []byte) error {
+	return l.Set(string(text))
+}
+
+// levelString returns the name of level
+func levelString(level Level) string {
+	levelsMu.RLock()
+	name, ok := levels[level]
+	levelsMu.RUnlock()
+	if !ok {
+		return "LEVEL(" + strconv.FormatUint(uint64(level), 10) + ")"
+	}
+	return name
+}
+
+// levelColor returns the color of level
+func levelColor(level Level) Color {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	if col, ok := levelColors[level]; ok {
+		return col
+	}
+	return ColorWhite
+}
+
+// levelColorSeq returns the ANSI sequence of the color of level
+func levelColorSeq(level Level, bold bool) string {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	col, ok := levelColors[level]
+	if !ok {
+		col = ColorWhite
+	}
+	if bold {
+		return colorSeqs[col][1]
+	}
+	return colorSeqs[col][0]
+}
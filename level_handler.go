@@ -0,0 +1,135 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxLevelBody is the size limit of the request body of LevelHandler
+const maxLevelBody = 4 << 10
+
+// levelPayload is the JSON body of the requests and responses of LevelHandler
+type levelPayload struct {
+	Level    *Level     `json:"level,omitempty"`
+	Duration string     `json:"duration,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// levelHandler serves the level of a logger over HTTP
+type levelHandler struct {
+	logger   *Logger // nil for the std logger
+	mu       sync.Mutex
+	timer    *time.Timer // reverts a timed override
+	previous Level       // level restored by timer
+	expires  time.Time
+}
+
+// LevelHandler returns an http.Handler reading and updating the level of l,
+// the std logger if l is nil. GET returns the current level:
+//
+//	{"level":"INFO"}
+//
+// PUT or POST updates it, with an optional duration after which the level
+// before the first pending override is restored, unless the level was
+// changed by other means in the meantime:
+//
+//	{"level":"debug","duration":"10m"}
+func LevelHandler(l *Logger) http.Handler {
+	return &levelHandler{logger: l}
+}
+
+func (h *levelHandler) target() *Logger {
+	if h.logger != nil {
+		return h.logger
+	}
+	return std()
+}
+
+// ServeHTTP implements the http.Handler interface
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.reply(w, http.StatusOK, "")
+	case http.MethodPut, http.MethodPost:
+		var req levelPayload
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLevelBody)).Decode(&req); err != nil {
+			h.reply(w, http.StatusBadRequest, fmt.Sprintf("bad request body: %v", err))
+			return
+		}
+		if req.Level == nil {
+			h.reply(w, http.StatusBadRequest, "missing level")
+			return
+		}
+		var duration time.Duration
+		if len(req.Duration) > 0 {
+			var err error
+			if duration, err = time.ParseDuration(req.Duration); err != nil || duration <= 0 {
+				h.reply(w, http.StatusBadRequest, fmt.Sprintf("bad duration %q", req.Duration))
+				return
+			}
+		}
+		h.set(*req.Level, duration)
+		h.reply(w, http.StatusOK, "")
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		h.reply(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method))
+	}
+}
+
+// set updates the level, restoring the previous one after duration if it is not 0
+func (h *levelHandler) set(level Level, duration time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l := h.target()
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	} else {
+		h.previous = l.Level()
+	}
+	h.expires = time.Time{}
+	l.SetLogLevel(level)
+	if duration > 0 {
+		h.expires = time.Now().Add(duration)
+		var timer *time.Timer
+		timer = time.AfterFunc(duration, func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.timer == timer {
+				if l.Level() == level {
+					l.SetLogLevel(h.previous)
+				}
+				h.timer = nil
+				h.expires = time.Time{}
+			}
+		})
+		h.timer = timer
+	}
+}
+
+func (h *levelHandler) reply(w http.ResponseWriter, status int, errMsg string) {
+	var resp levelPayload
+	if len(errMsg) > 0 {
+		resp.Error = errMsg
+	} else {
+		level := h.target().Level()
+		resp.Level = &level
+		h.mu.Lock()
+		if !h.expires.IsZero() {
+			expires := h.expires
+			resp.Expires = &expires
+		}
+		h.mu.Unlock()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(&resp)
+}
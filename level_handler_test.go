@@ -0,0 +1,92 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveLevel(t *testing.T, h http.Handler, method, body string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, "/log/level", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code, strings.TrimSpace(rec.Body.String())
+}
+
+func TestLevelHandler(t *testing.T) {
+	l := New(&bytes.Buffer{}, "", 0)
+	l.SetLogLevel(INFO)
+	h := LevelHandler(l)
+
+	if code, body := serveLevel(t, h, http.MethodGet, ""); code != http.StatusOK || body != `{"level":"INFO"}` {
+		t.Errorf("GET: %d %s", code, body)
+	}
+	if code, body := serveLevel(t, h, http.MethodPut, `{"level":"warn"}`); code != http.StatusOK || body != `{"level":"WARN"}` {
+		t.Errorf("PUT: %d %s", code, body)
+	}
+	if l.Level() != WARN {
+		t.Errorf("level not updated: %v", l.Level())
+	}
+	large := `{"level":"debug","duration":"` + strings.Repeat(" ", maxLevelBody) + `"}`
+	for _, body := range []string{`{"level":"loud"}`, `{}`, `{"level":"debug","duration":"-1s"}`, `not json`, large} {
+		if code, resp := serveLevel(t, h, http.MethodPost, body); code != http.StatusBadRequest {
+			t.Errorf("POST %s: %d %s", body, code, resp)
+		}
+	}
+	if code, _ := serveLevel(t, h, http.MethodDelete, ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE: %d", code)
+	}
+}
+
+func TestLevelHandlerTimedOverride(t *testing.T) {
+	l := New(&bytes.Buffer{}, "", 0)
+	l.SetLogLevel(INFO)
+	h := LevelHandler(nil) // std logger is l
+
+	code, body := serveLevel(t, h, http.MethodPost, `{"level":"debug","duration":"50ms"}`)
+	if code != http.StatusOK || !strings.Contains(body, `"expires"`) || l.Level() != DEBUG {
+		t.Fatalf("POST: %d %s level %v", code, body, l.Level())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for l.Level() != INFO && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if l.Level() != INFO {
+		t.Errorf("level not reverted: %v", l.Level())
+	}
+	if _, body := serveLevel(t, h, http.MethodGet, ""); body != `{"level":"INFO"}` {
+		t.Errorf("GET after revert: %s", body)
+	}
+}
+
+func TestLevelHandlerKeepsDirectChange(t *testing.T) {
+	l := New(&bytes.Buffer{}, "", 0)
+	l.SetLogLevel(INFO)
+	h := LevelHandler(l).(*levelHandler)
+
+	if code, body := serveLevel(t, h, http.MethodPut, `{"level":"debug","duration":"50ms"}`); code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", code, body)
+	}
+	l.SetLogLevel(WARN)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		h.mu.Lock()
+		pending := h.timer != nil
+		h.mu.Unlock()
+		if !pending {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if l.Level() != WARN {
+		t.Errorf("direct change reverted: %v", l.Level())
+	}
+}
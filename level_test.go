@@ -0,0 +1,102 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"testing"
+)
+
+func TestTraceLevel(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	l.Trace("hidden")
+	l.SetLogLevel(TRACE)
+	l.Tracef("shown %d", 1)
+	if want := "[TRACE] shown 1\n"; b.String() != want {
+		t.Errorf("got %q; want %q", b.String(), want)
+	}
+}
+
+func TestRegisterLevel(t *testing.T) {
+	const NOTICE = WARN + 5
+	if err := RegisterLevel(NOTICE, "NOTICE", ColorBlue); err != nil {
+		t.Fatal(err)
+	}
+	for _, level := range []Level{0, 5, FATAL, PANIC, INFO, TRACE} {
+		if err := RegisterLevel(level, "X"); err == nil {
+			t.Errorf("level %d: reserved level registered", level)
+		}
+	}
+	for _, name := range []string{"notice", "Warn", "warning"} {
+		if err := RegisterLevel(NOTICE+1, name); err == nil {
+			t.Errorf("%s: duplicate level name registered", name)
+		}
+	}
+
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	l.SetLogLevel(WARN)
+	l.Log(NOTICE, "hidden")
+	l.SetLogLevel(INFO)
+	l.Logw(NOTICE, "shown", "k", 1)
+	if want := "[NOTICE] shown k=1\n"; b.String() != want {
+		t.Errorf("got %q; want %q", b.String(), want)
+	}
+	if levelColor(NOTICE) != ColorBlue {
+		t.Errorf("got color %d", levelColor(NOTICE))
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{
+		"warn": WARN, " WARNING ": WARN, "Info": INFO, "trace": TRACE, "fatal": FATAL, "panic": PANIC,
+		"err": ERROR, "none": none, "30": WARN, "3": WARN, "5": DEBUG,
+	}
+	for s, want := range tests {
+		got, err := ParseLevel(s)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+	if WARN.String() != "WARN" {
+		t.Errorf("got %q", WARN.String())
+	}
+}
+
+func TestLevelMarshal(t *testing.T) {
+	var config struct {
+		Level Level `json:"level"`
+	}
+	if err := json.Unmarshal([]byte(`{"level":"debug"}`), &config); err != nil || config.Level != DEBUG {
+		t.Fatalf("got %v, %v", config.Level, err)
+	}
+	data, err := json.Marshal(config)
+	if err != nil || string(data) != `{"level":"DEBUG"}` {
+		t.Errorf("got %s, %v", data, err)
+	}
+
+	unregistered := INFO + 7
+	text, err := unregistered.MarshalText()
+	if err != nil || string(text) != "LEVEL(47)" {
+		t.Fatalf("got %s, %v", text, err)
+	}
+	var parsed Level
+	if err := parsed.UnmarshalText(text); err != nil || parsed != unregistered {
+		t.Errorf("got %v, %v; want %v", parsed, err, unregistered)
+	}
+
+	level := INFO
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&level, "level", "log level")
+	if err := fs.Parse([]string{"-level", "error"}); err != nil || level != ERROR {
+		t.Errorf("got %v, %v", level, err)
+	}
+}
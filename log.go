@@ -11,11 +11,15 @@
 // of each logged message.
 // Every log message is output on a separate line: if the message being
 // printed does not end in a newline, the logger will add one.
-// The Fatal functions call os.Exit(1) after writing the log message.
-// The Panic functions call panic after writing the log message.
+// The Fatal functions flush the logger, run the exit hooks and call os.Exit(1)
+// or the function set by SetExitFunc after writing the log message.
+// The Panic functions write the message with the call stack at PANIC level,
+// wait until it is written and call panic.
 package log
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
 	"sync"
@@ -23,30 +27,7 @@ import (
 	"time"
 )
 
-// Level log level
-type Level uint32
-
-// supported levels
-const (
-	none Level = iota
-	FATAL
-	ERROR
-	WARN
-	INFO
-	DEBUG
-)
-
-var (
-	logger atomic.Value // holds current server configuration
-	levels = map[Level]string{
-		none:  "",
-		FATAL: "FATAL",
-		ERROR: "ERROR",
-		WARN:  " WARN",
-		INFO:  " INFO",
-		DEBUG: "DEBUG",
-	}
-)
+var logger atomic.Value // holds current server configuration
 
 // These flags define which text to prefix to each log entry generated by the Logger.
 // Bits are or'ed together to control what's printed.
@@ -71,6 +52,7 @@ const (
 	Lshortfunc                    // short function name: printf
 	Lsequence                     // write log sequence id
 	Lcolor                        // colorful log when output is tty
+	Lstacktrace                   // call stack of the records at the stack level of the logger or more severe, see SetStackLevel
 	LstdFlags     = Ldate | Ltime // initial values for the standard logger
 	Lfull         = Ldate | Ltime | Lmicroseconds | Lshortfile | Lloggername /*| Lgoroutineid*/ | Lshortfunc | Lsequence | Lcolor
 )
@@ -85,8 +67,19 @@ type Logger struct {
 	mu      sync.Mutex // ensures atomic writes; protects the following fields
 	prefix  string     // prefix to write at beginning of each line
 	flag    int        // properties
-	backend Backend    // default destination for output
+	backend Backend    // default destination for output, nil for child loggers
 	name    string     // logger name if empty use process name
+	fields  []Field    // key/value pairs attached to every record
+	format  Formatter  // formatter of records if the backend has none
+	root    *Logger    // logger sharing its level and sequence, nil if this is a root logger
+
+	stackLevel Level // least severe level getting the call stack with Lstacktrace, ERROR if 0
+
+	timeFormat TimeFormat     // format of the record time
+	location   *time.Location // time zone of the record time, overrides LUTC
+	modules    atomic.Value   // holds *moduleLevels of the root logger
+	hooks      *levelHooks    // hooks of the root logger, nil if none is added
+	onError    func(error)    // error handler of the root logger, nil for the default one
 }
 
 // New creates a new Logger. The out variable sets the
@@ -125,14 +118,14 @@ func std() *Logger {
 func (l *Logger) Writer() io.Writer {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.backend.Writer()
+	return l.sink().Writer()
 }
 
 // SetOutput sets the output destination for the logger.
 func (l *Logger) SetOutput(w io.Writer) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.backend.SetWriter(w)
+	l.sink().SetWriter(w)
 }
 
 // Name returns the name of current logger
@@ -145,18 +138,69 @@ func (l *Logger) Name() string {
 // SetName sets the name for the logger.
 func (l *Logger) SetName(name string) {
 	l.mu.Lock()
-	defer l.mu.Unlock()
 	l.name = name
+	l.mu.Unlock()
+	l.resetModuleCache()
+}
+
+// Formatter returns the formatter of the logger, nil means the default TextFormatter
+func (l *Logger) Formatter() Formatter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.format
+}
+
+// SetFormatter sets the formatter of the logger, a formatter set on the
+// backend takes precedence over it.
+func (l *Logger) SetFormatter(f Formatter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = f
 }
 
 // Level returns current level of logger
 func (l *Logger) Level() Level {
-	return Level(atomic.LoadUint32((*uint32)(&l.level)))
+	return Level(atomic.LoadUint32((*uint32)(&l.base().level)))
 }
 
 // SetLogLevel update the logger's level
 func (l *Logger) SetLogLevel(level Level) {
-	atomic.StoreUint32((*uint32)(&l.level), uint32(level))
+	atomic.StoreUint32((*uint32)(&l.base().level), uint32(level))
+}
+
+// base returns the logger owning the level and sequence of l
+func (l *Logger) base() *Logger {
+	if l.root != nil {
+		return l.root
+	}
+	return l
+}
+
+// sink returns the backend of l, child loggers use the backend of their
+// root so that SetOutput on any of them reaches all
+func (l *Logger) sink() Backend {
+	return l.base().backend
+}
+
+// With returns a child logger attaching the given key/value pairs to every
+// record it writes. keyvals are alternating keys and values, Field values
+// are added as they are. The child shares backend, level and sequence
+// with l.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return &Logger{
+		prefix: l.prefix,
+		flag:   l.flag,
+		name:   l.name,
+		fields: joinFields(l.fields, makeFields(keyvals)),
+		format: l.format,
+		root:   l.base(),
+
+		stackLevel: l.stackLevel,
+		timeFormat: l.timeFormat,
+		location:   l.location,
+	}
 }
 
 // Flags returns the output flags for the logger.
@@ -194,67 +238,159 @@ func (l *Logger) SetPrefix(prefix string) {
 // provided for generality, although at the moment on all pre-defined
 // paths it will be 2.
 func (l *Logger) Output(calldepth int, s string) error {
-	l.output(calldepth, none, writeModeLog, nil, s)
+	l.output(calldepth, none, writeModeLog, nil, nil, s)
 	return nil
 }
 
-func (l *Logger) output(calldepth int, level Level, mode writeMode, format *string, v ...interface{}) {
+func (l *Logger) output(calldepth int, level Level, mode writeMode, format *string, fields []Field, v ...interface{}) {
 	now := time.Now() // get this early.
-	var file, function string
-	var line int
-	l.mu.Lock()
-	defer l.mu.Unlock()
+	if level == FATAL {
+		// deferred before unlock to exit after the lock is released
+		defer l.fatal()
+	}
+	l.lock()
+	defer l.unlock()
+
+	r := &Record{
+		time:   now,
+		level:  level,
+		fmt:    format,
+		args:   v,
+		fields: fields,
+		mode:   mode,
+	}
 	if l.flag&(Lshortfile|Llongfile|Lshortfunc|Llongfunc) != 0 {
 		// Release lock while getting caller info - it's expensive.
-		function, file, line = getRuntimeInfo(calldepth)
+		var file, function string
+		r.pc, function, file, r.line = callerInfo(calldepth)
+		r.file, r.function = &file, &function
+	}
+	if l.wantStack(level) {
+		r.stack = formatStack(callers(calldepth))
 	}
+	l.emit(r)
+}
 
-	r := &Record{
-		index:    l.index,
-		time:     now,
-		prefix:   &l.prefix,
-		module:   &l.name,
-		level:    level,
-		file:     &file,
-		line:     line,
-		function: &function,
-		fmt:      format,
-		args:     v,
-		flag:     l.flag,
-		mode:     mode,
-		newline:  true,
+// emit completes r with the settings of the logger and hands it to the backend,
+// the caller must hold the lock of l
+func (l *Logger) emit(r *Record) {
+	if l.location != nil {
+		r.time = r.time.In(l.location)
+	} else if l.flag&LUTC != 0 {
+		r.time = r.time.UTC()
+	}
+	r.index = l.logIndex()
+	r.prefix = &l.prefix
+	r.module = &l.name
+	r.fields = joinFields(l.fields, r.fields)
+	r.flag = l.flag
+	r.formatter = l.format
+	r.timeFormat = l.timeFormat
+	r.onError = l.base().onError
+	if hooks := l.base().hooks; hooks != nil {
+		hooks.fire(r)
 	}
 
-	l.backend.log(r)
+	l.sink().log(r)
 	l.updateLogIndex()
 }
 
+// lock locks the logger, records of child loggers are serialized with their root
+func (l *Logger) lock() {
+	l.mu.Lock()
+	if l.root != nil {
+		l.root.mu.Lock()
+	}
+}
+
+func (l *Logger) unlock() {
+	if l.root != nil {
+		l.root.mu.Unlock()
+	}
+	l.mu.Unlock()
+}
+
 func (l *Logger) log(level Level, v ...interface{}) {
-	if level <= Level(atomic.LoadUint32((*uint32)(&l.level))) {
-		l.output(4, level, writeModeLog, nil, v...)
+	if l.enabled(level, 2) {
+		l.output(4, level, writeModeLog, nil, nil, v...)
 	}
 }
 
 func (l *Logger) logf(level Level, format string, v ...interface{}) {
-	if level <= Level(atomic.LoadUint32((*uint32)(&l.level))) {
-		l.output(4, level, writeModeLogf, &format, v...)
+	if l.enabled(level, 2) {
+		l.output(4, level, writeModeLogf, &format, nil, v...)
 	}
 }
 
 func (l *Logger) logln(level Level, v ...interface{}) {
-	if level <= Level(atomic.LoadUint32((*uint32)(&l.level))) {
-		l.output(4, level, writeModeLogln, nil, v...)
+	if l.enabled(level, 2) {
+		l.output(4, level, writeModeLogln, nil, nil, v...)
+	}
+}
+
+func (l *Logger) logw(level Level, msg string, keyvals ...interface{}) {
+	if l.enabled(level, 2) {
+		l.output(4, level, writeModeLog, nil, makeFields(keyvals), msg)
+	}
+}
+
+// logPanic writes s at PANIC, waits until it is written and panics with s
+func (l *Logger) logPanic(s string) {
+	if l.enabled(PANIC, 2) {
+		l.output(4, PANIC, writeModeLog, nil, nil, s)
+		l.flushPending("panic")
 	}
+	panic(s)
 }
 
 // updateLogIndex updates current logger index
 func (l *Logger) updateLogIndex() {
-	atomic.AddUint64(&l.index, 1)
+	atomic.AddUint64(&l.base().index, 1)
 }
 
 // logIndex load current index
 func (l *Logger) logIndex() uint64 {
-	return atomic.LoadUint64(&l.index)
+	return atomic.LoadUint64(&l.base().index)
+}
+
+// Log prints log at the given level, which can be a custom level registered by RegisterLevel.
+func (l *Logger) Log(level Level, v ...interface{}) {
+	l.log(level, v...)
+}
+
+// Logln prints log at the given level with newline.
+func (l *Logger) Logln(level Level, v ...interface{}) {
+	l.logln(level, v...)
+}
+
+// Logf prints formatted log at the given level.
+func (l *Logger) Logf(level Level, format string, v ...interface{}) {
+	l.logf(level, format, v...)
+}
+
+// Logw prints log at the given level with the given key/value pairs.
+func (l *Logger) Logw(level Level, msg string, keyvals ...interface{}) {
+	l.logw(level, msg, keyvals...)
+}
+
+// Trace prints trace log.
+func (l *Logger) Trace(v ...interface{}) {
+	l.log(TRACE, v...)
+}
+
+// Traceln prints trace log with newline.
+func (l *Logger) Traceln(v ...interface{}) {
+	l.logln(TRACE, v...)
+}
+
+// Tracef prints formatted trace log.
+func (l *Logger) Tracef(format string, v ...interface{}) {
+	l.logf(TRACE, format, v...)
+}
+
+// Tracew prints trace log with the given key/value pairs.
+func (l *Logger) Tracew(msg string, keyvals ...interface{}) {
+	l.logw(TRACE, msg, keyvals...)
 }
 
 // Debug prints debug log.
@@ -272,6 +408,11 @@ func (l *Logger) Debugf(format string, v ...interface{}) {
 	l.logf(DEBUG, format, v...)
 }
 
+// Debugw prints debug log with the given key/value pairs.
+func (l *Logger) Debugw(msg string, keyvals ...interface{}) {
+	l.logw(DEBUG, msg, keyvals...)
+}
+
 // Info prints info log.
 func (l *Logger) Info(v ...interface{}) {
 	l.log(INFO, v...)
@@ -287,6 +428,11 @@ func (l *Logger) Infof(format string, v ...interface{}) {
 	l.logf(INFO, format, v...)
 }
 
+// Infow prints info log with the given key/value pairs.
+func (l *Logger) Infow(msg string, keyvals ...interface{}) {
+	l.logw(INFO, msg, keyvals...)
+}
+
 // Warn prints warning log.
 func (l *Logger) Warn(v ...interface{}) {
 	l.log(WARN, v...)
@@ -302,6 +448,11 @@ func (l *Logger) Warnf(format string, v ...interface{}) {
 	l.logf(WARN, format, v...)
 }
 
+// Warnw prints warning log with the given key/value pairs.
+func (l *Logger) Warnw(msg string, keyvals ...interface{}) {
+	l.logw(WARN, msg, keyvals...)
+}
+
 // Error prints error log.
 func (l *Logger) Error(v ...interface{}) {
 	l.log(ERROR, v...)
@@ -317,6 +468,11 @@ func (l *Logger) Errorf(format string, v ...interface{}) {
 	l.logf(ERROR, format, v...)
 }
 
+// Errorw prints error log with the given key/value pairs.
+func (l *Logger) Errorw(msg string, keyvals ...interface{}) {
+	l.logw(ERROR, msg, keyvals...)
+}
+
 // Fatal prints fatal log and exit current process.
 func (l *Logger) Fatal(v ...interface{}) {
 	l.log(FATAL, v...)
@@ -332,27 +488,58 @@ func (l *Logger) Fatalf(format string, v ...interface{}) {
 	l.logf(FATAL, format, v...)
 }
 
+// Fatalw prints fatal log with the given key/value pairs and exit current process.
+func (l *Logger) Fatalw(msg string, keyvals ...interface{}) {
+	l.logw(FATAL, msg, keyvals...)
+}
+
+// Panic prints panic log with the call stack and calls panic.
+func (l *Logger) Panic(v ...interface{}) {
+	l.logPanic(fmt.Sprint(v...))
+}
+
+// Panicln prints panic log with newline and the call stack and calls panic.
+func (l *Logger) Panicln(v ...interface{}) {
+	l.logPanic(fmt.Sprintln(v...))
+}
+
+// Panicf prints formatted panic log with the call stack and calls panic.
+func (l *Logger) Panicf(format string, v ...interface{}) {
+	l.logPanic(fmt.Sprintf(format, v...))
+}
+
 // Print calls Output to print to the standard logger.
 // Arguments are handled in the manner of fmt.Print.
 func (l *Logger) Print(v ...interface{}) {
-	l.output(3, none, writeModeLog, nil, v...)
+	l.output(3, none, writeModeLog, nil, nil, v...)
 }
 
 // Printf calls Output to print to the standard logger.
 // Arguments are handled in the manner of fmt.Printf.
 func (l *Logger) Printf(format string, v ...interface{}) {
-	l.output(3, none, writeModeLogf, &format, v...)
+	l.output(3, none, writeModeLogf, &format, nil, v...)
 }
 
 // Println calls Output to print to the standard logger.
 // Arguments are handled in the manner of fmt.Println.
 func (l *Logger) Println(v ...interface{}) {
-	l.output(3, none, writeModeLogln, nil, v...)
+	l.output(3, none, writeModeLogln, nil, nil, v...)
 }
 
-// Flush flush current logger
+// Flush waits until the records logged so far are written, the logger keeps working
 func (l *Logger) Flush() {
-	l.backend.Flush()
+	_ = l.FlushContext(context.Background())
+}
+
+// FlushContext waits until the records logged so far are written or ctx is done
+func (l *Logger) FlushContext(ctx context.Context) error {
+	return l.sink().Flush(ctx)
+}
+
+// Close flushes and stops the backend of the logger, it returns ctx.Err()
+// if ctx is done before the backend is stopped
+func (l *Logger) Close(ctx context.Context) error {
+	return l.sink().Close(ctx)
 }
 
 // These functions write to the standard logger.
@@ -367,6 +554,11 @@ func SetOutput(w io.Writer) {
 	std().SetOutput(w)
 }
 
+// SetFormatter sets the formatter of the std logger.
+func SetFormatter(f Formatter) {
+	std().SetFormatter(f)
+}
+
 // Name returns the name of the std logger
 func Name() string {
 	return std().Name()
@@ -402,6 +594,51 @@ func SetPrefix(prefix string) {
 	std().SetPrefix(prefix)
 }
 
+// With returns a child of the std logger with the given key/value pairs.
+func With(keyvals ...interface{}) *Logger {
+	return std().With(keyvals...)
+}
+
+// Log prints log at the given level, which can be a custom level registered by RegisterLevel.
+func Log(level Level, v ...interface{}) {
+	std().log(level, v...)
+}
+
+// Logln prints log at the given level with newline.
+func Logln(level Level, v ...interface{}) {
+	std().logln(level, v...)
+}
+
+// Logf prints formatted log at the given level.
+func Logf(level Level, format string, v ...interface{}) {
+	std().logf(level, format, v...)
+}
+
+// Logw prints log at the given level with the given key/value pairs.
+func Logw(level Level, msg string, keyvals ...interface{}) {
+	std().logw(level, msg, keyvals...)
+}
+
+// Trace prints trace log.
+func Trace(v ...interface{}) {
+	std().log(TRACE, v...)
+}
+
+// Traceln prints trace log with newline.
+func Traceln(v ...interface{}) {
+	std().logln(TRACE, v...)
+}
+
+// Tracef prints formatted trace log.
+func Tracef(format string, v ...interface{}) {
+	std().logf(TRACE, format, v...)
+}
+
+// Tracew prints trace log with the given key/value pairs.
+func Tracew(msg string, keyvals ...interface{}) {
+	std().logw(TRACE, msg, keyvals...)
+}
+
 // Debug prints debug log.
 func Debug(v ...interface{}) {
 	std().log(DEBUG, v...)
@@ -417,6 +654,11 @@ func Debugf(format string, v ...interface{}) {
 	std().logf(DEBUG, format, v...)
 }
 
+// Debugw prints debug log with the given key/value pairs.
+func Debugw(msg string, keyvals ...interface{}) {
+	std().logw(DEBUG, msg, keyvals...)
+}
+
 // Info prints info log.
 func Info(v ...interface{}) {
 	std().log(INFO, v...)
@@ -432,6 +674,11 @@ func Infof(format string, v ...interface{}) {
 	std().logf(INFO, format, v...)
 }
 
+// Infow prints info log with the given key/value pairs.
+func Infow(msg string, keyvals ...interface{}) {
+	std().logw(INFO, msg, keyvals...)
+}
+
 // Warn prints warning log.
 func Warn(v ...interface{}) {
 	std().log(WARN, v...)
@@ -447,6 +694,11 @@ func Warnf(format string, v ...interface{}) {
 	std().logf(WARN, format, v...)
 }
 
+// Warnw prints warning log with the given key/value pairs.
+func Warnw(msg string, keyvals ...interface{}) {
+	std().logw(WARN, msg, keyvals...)
+}
+
 // Error prints error log.
 func Error(v ...interface{}) {
 	std().log(ERROR, v...)
@@ -462,6 +714,11 @@ func Errorf(format string, v ...interface{}) {
 	std().logf(ERROR, format, v...)
 }
 
+// Errorw prints error log with the given key/value pairs.
+func Errorw(msg string, keyvals ...interface{}) {
+	std().logw(ERROR, msg, keyvals...)
+}
+
 // Fatal prints fatal log and exit current process.
 func Fatal(v ...interface{}) {
 	std().log(FATAL, v...)
@@ -477,41 +734,67 @@ func Fatalf(format string, v ...interface{}) {
 	std().logf(FATAL, format, v...)
 }
 
+// Fatalw prints fatal log with the given key/value pairs and exit current process.
+func Fatalw(msg string, keyvals ...interface{}) {
+	std().logw(FATAL, msg, keyvals...)
+}
+
+// Panic prints panic log with the call stack and calls panic.
+func Panic(v ...interface{}) {
+	std().logPanic(fmt.Sprint(v...))
+}
+
+// Panicln prints panic log with newline and the call stack and calls panic.
+func Panicln(v ...interface{}) {
+	std().logPanic(fmt.Sprintln(v...))
+}
+
+// Panicf prints formatted panic log with the call stack and calls panic.
+func Panicf(format string, v ...interface{}) {
+	std().logPanic(fmt.Sprintf(format, v...))
+}
+
 // Print calls Output to print to the standard logger.
 // Arguments are handled in the manner of fmt.Print.
 func Print(v ...interface{}) {
-	std().output(3, none, writeModeLog, nil, v...)
+	std().output(3, none, writeModeLog, nil, nil, v...)
 }
 
 // Printf calls Output to print to the standard logger.
 // Arguments are handled in the manner of fmt.Printf.
 func Printf(format string, v ...interface{}) {
-	std().output(3, none, writeModeLogf, &format, v...)
+	std().output(3, none, writeModeLogf, &format, nil, v...)
 }
 
 // Println calls Output to print to the standard logger.
 // Arguments are handled in the manner of fmt.Println.
 func Println(v ...interface{}) {
-	std().output(3, none, writeModeLogln, nil, v...)
+	std().output(3, none, writeModeLogln, nil, nil, v...)
 }
 
 // Flush std logger
 func Flush() {
-	std().backend.Flush()
+	std().Flush()
+}
+
+// FlushContext flushes the std logger until ctx is done
+func FlushContext(ctx context.Context) error {
+	return std().FlushContext(ctx)
+}
+
+// Close flushes and stops the backend of the std logger
+func Close(ctx context.Context) error {
+	return std().Close(ctx)
 }
 
 func writeLog(backend Backend, r *Record) {
-	if r.flag&Lcolor != 0 {
+	if r.colored(backend) {
 		if backend.isatty() {
 			r.colorfultty(backend, false)
 		} else {
 			r.colorful(backend, false)
 		}
 	} else {
-		_ = backend.write(r.msgBuf())
-	}
-
-	if r.level == FATAL {
-		os.Exit(1)
+		r.writeTo(backend, "")
 	}
 }
@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LogfmtFormatter writes each record as a single line of logfmt pairs, the
+// keys are the same as the ones of JSONFormatter plus prefix for a non
+// empty logger prefix:
+//
+//	level=info ts=2019-01-23T01:23:23.123123+08:00 msg="hello world" user=john
+//
+// Values containing spaces, '=', quotes or control characters are quoted,
+// newlines of a multi-line message are escaped. A field whose key clashes
+// with one of these keys is written with JSONFieldPrefix.
+type LogfmtFormatter struct {
+	// TimeFormat is the format of the ts value, if empty the format of the
+	// logger is used or TimeRFC3339Nano for TimeDefault
+	TimeFormat TimeFormat
+}
+
+// Format implements the Formatter interface
+func (f *LogfmtFormatter) Format(r *Record, buf []byte) []byte {
+	start := len(buf)
+	pair := func(key, value string) {
+		if len(buf) > start {
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, key...)
+		buf = append(buf, '=')
+		appendValue(&buf, value)
+	}
+
+	if r.level > none {
+		pair(JSONLevelKey, strings.ToLower(levelString(r.level)))
+	}
+	if r.flag&(Ldate|Ltime|Lmicroseconds) != 0 && !r.time.IsZero() {
+		pair(JSONTimeKey, string(appendTime(nil, r.time, recordTimeFormat(f.TimeFormat, r))))
+	}
+	if len(r.Prefix()) > 0 {
+		pair("prefix", r.Prefix())
+	}
+	if r.flag&Lsequence != 0 {
+		pair(JSONSequenceKey, strconv.FormatUint(r.index, 10))
+	}
+	if r.flag&Lloggername != 0 && len(r.Name()) > 0 {
+		pair(JSONNameKey, r.Name())
+	}
+	if r.flag&Lgoroutineid != 0 {
+		pair(JSONGoroutineKey, id())
+	}
+	if r.flag&(Lshortfile|Llongfile) != 0 {
+		file := r.File()
+		if r.flag&Lshortfile != 0 {
+			file = splitLast(file, '/')
+		}
+		pair(JSONCallerKey, file+":"+strconv.Itoa(r.line))
+	}
+	if r.flag&(Lshortfunc|Llongfunc) != 0 {
+		function := r.Function()
+		if r.flag&Lshortfunc != 0 {
+			function = splitLast(function, '.')
+		}
+		pair(JSONFunctionKey, function)
+	}
+	pair(JSONMessageKey, r.Message())
+	for i, field := range r.fields {
+		group := ""
+		if logfmtReserved(field.Key) {
+			group = JSONFieldPrefix
+		}
+		appendGroup(&buf, group, r.fields[i:i+1])
+	}
+	if len(r.stack) > 0 {
+		pair(JSONStackKey, strings.Join(r.stack, "\n"))
+	}
+	return append(buf, '\n')
+}
+
+// logfmtReserved reports whether key is one of the keys of LogfmtFormatter
+func logfmtReserved(key string) bool {
+	switch key {
+	case JSONLevelKey, JSONTimeKey, "prefix", JSONSequenceKey, JSONNameKey,
+		JSONGoroutineKey, JSONCallerKey, JSONFunctionKey, JSONMessageKey, JSONStackKey:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLogfmtFormatter(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Lsequence|Lloggername)
+	defer l.SetFormatter(nil)
+	l.SetName("app")
+	l.SetFormatter(&LogfmtFormatter{})
+	l.With("http", []Field{{"method", "GET"}, {"path", "/a b"}}).Warnw("first line\nsecond \"line\"", "k=v", "x=1", "empty", "")
+	want := `level=warn seq=0 logger=app msg="first line\nsecond \"line\"" http.method=GET http.path="/a b" k_v="x=1" empty=""` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestLogfmtReservedKeys(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	l.SetFormatter(&LogfmtFormatter{})
+	l.With("msg", "field").Infow("message", "level", 1, "user", "john")
+	want := `level=info msg=message fields.msg=field fields.level=1 user=john` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// moduleRule overrides the level of the call sites matching pattern
+type moduleRule struct {
+	pattern string
+	level   Level
+}
+
+// moduleKey identifies a call site of the loggers named name
+type moduleKey struct {
+	pc   uintptr
+	name string
+}
+
+// moduleLevels is an immutable set of rules with the cached decision of
+// each call site, it is replaced as a whole when the rules change
+type moduleLevels struct {
+	rules []moduleRule
+	cache sync.Map // moduleKey -> *moduleRule, nil if no rule matches
+	names sync.Map // logger name -> *moduleRule of the last rule if it matches the name
+}
+
+// SetModuleLevel overrides the level of the records written from the
+// modules matching pattern. pattern is a path.Match pattern matched
+// against the logger name, the package path of the caller (net/http), its
+// last element (http) and the caller's file name without extension
+// (server). Rules set later take precedence over earlier ones. The rules
+// are shared by the children created by With.
+func (l *Logger) SetModuleLevel(pattern string, level Level) {
+	root := l.base()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	var rules []moduleRule
+	if m := root.moduleLevels(); m != nil {
+		for _, rule := range m.rules {
+			if rule.pattern != pattern {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	rules = append(rules, moduleRule{pattern: pattern, level: level})
+	root.modules.Store(&moduleLevels{rules: rules})
+}
+
+// SetModuleLevels sets module levels from a comma separated list of
+// pattern=level pairs such as "net/*=debug,db=warn"
+func (l *Logger) SetModuleLevels(spec string) error {
+	var rules []moduleRule
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		i := strings.LastIndexByte(item, '=')
+		if i <= 0 {
+			return fmt.Errorf("log: bad module level %q", item)
+		}
+		pattern := strings.TrimSpace(item[:i])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("log: bad module pattern %q: %v", pattern, err)
+		}
+		level, err := ParseLevel(item[i+1:])
+		if err != nil {
+			return err
+		}
+		rules = append(rules, moduleRule{pattern: pattern, level: level})
+	}
+	for _, rule := range rules {
+		l.SetModuleLevel(rule.pattern, rule.level)
+	}
+	return nil
+}
+
+// ClearModuleLevels removes all module levels
+func (l *Logger) ClearModuleLevels() {
+	root := l.base()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	root.modules.Store((*moduleLevels)(nil))
+}
+
+// moduleLevels returns the module levels of the root logger, nil if none is set
+func (l *Logger) moduleLevels() *moduleLevels {
+	m, _ := l.base().modules.Load().(*moduleLevels)
+	return m
+}
+
+// resetModuleCache drops the cached decisions, the logger names may have changed
+func (l *Logger) resetModuleCache() {
+	root := l.base()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	if m := root.moduleLevels(); m != nil {
+		root.modules.Store(&moduleLevels{rules: m.rules})
+	}
+}
+
+// enabled reports whether a record at level is written. calldepth is
+// the number of frames from the caller of enabled to the user code.
+func (l *Logger) enabled(level Level, calldepth int) bool {
+	m := l.moduleLevels()
+	if m == nil || len(m.rules) == 0 {
+		return level <= l.Level()
+	}
+	if rule := m.matchName(l.Name()); rule != nil {
+		// no rule taking precedence is left to match the call site
+		return level <= rule.level
+	}
+
+	var pcs [1]uintptr
+	if runtime.Callers(calldepth+2, pcs[:]) == 0 {
+		return level <= l.Level()
+	}
+	return l.enabledAt(m, level, pcs[0])
+}
+
+// enabledPC reports whether a record at level written from pc is written,
+// pc is 0 if the call site is unknown
+func (l *Logger) enabledPC(level Level, pc uintptr) bool {
+	m := l.moduleLevels()
+	if m == nil || len(m.rules) == 0 || pc == 0 {
+		return level <= l.Level()
+	}
+	return l.enabledAt(m, level, pc)
+}
+
+// mayBeEnabled reports whether a record at level is written from any call site
+func (l *Logger) mayBeEnabled(level Level) bool {
+	if level <= l.Level() {
+		return true
+	}
+	if m := l.moduleLevels(); m != nil {
+		for _, rule := range m.rules {
+			if level <= rule.level {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (l *Logger) enabledAt(m *moduleLevels, level Level, pc uintptr) bool {
+	name := l.Name()
+	key := moduleKey{pc: pc, name: name}
+	rule, ok := m.cache.Load(key)
+	if !ok {
+		rule = m.match(pc, name)
+		m.cache.Store(key, rule)
+	}
+	if r := rule.(*moduleRule); r != nil {
+		return level <= r.level
+	}
+	return level <= l.Level()
+}
+
+// matchName returns the last rule if it matches the logger name, the
+// decision does not depend on the call site then
+func (m *moduleLevels) matchName(name string) *moduleRule {
+	if rule, ok := m.names.Load(name); ok {
+		return rule.(*moduleRule)
+	}
+	var rule *moduleRule
+	last := &m.rules[len(m.rules)-1]
+	if ok, _ := path.Match(last.pattern, name); ok && len(name) > 0 {
+		rule = last
+	}
+	m.names.Store(name, rule)
+	return rule
+}
+
+// match returns the last rule matching the call site at pc or the logger name
+func (m *moduleLevels) match(pc uintptr, name string) *moduleRule {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	pkg := packagePath(frame.Function)
+	candidates := []string{name, pkg, splitLast(pkg, '/'), baseName(frame.File)}
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		for _, candidate := range candidates {
+			if len(candidate) == 0 {
+				continue
+			}
+			if ok, _ := path.Match(m.rules[i].pattern, candidate); ok {
+				return &m.rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// packagePath returns the package path of a full function name such as
+// github.com/mysqto/log.(*Logger).Info
+func packagePath(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	if dot := strings.IndexByte(function[slash+1:], '.'); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+// baseName returns the final element of a slash separated file name without extension
+func baseName(file string) string {
+	return name(splitLast(file, '/'))
+}
+
+// SetModuleLevel overrides the level of modules matching pattern for the std logger
+func SetModuleLevel(pattern string, level Level) {
+	std().SetModuleLevel(pattern, level)
+}
+
+// SetModuleLevels sets module levels of the std logger from a spec such as "net/*=debug,db=warn"
+func SetModuleLevels(spec string) error {
+	return std().SetModuleLevels(spec)
+}
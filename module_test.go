@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestModuleLevel(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	l.SetLogLevel(WARN)
+	if err := l.SetModuleLevels("github.com/mysqto/*=debug, db=error"); err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("package")
+	db := l.With()
+	db.SetName("db")
+	db.Warn("hidden")
+	db.Error("db")
+	l.SetModuleLevel("module_test", TRACE)
+	l.Trace("file")
+	Trace("std")
+	l.ClearModuleLevels()
+	l.Info("cleared")
+
+	want := "[DEBUG] package\n[ERROR] db\n[TRACE] file\n[TRACE] std\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestModuleCacheWith(t *testing.T) {
+	l := New(&bytes.Buffer{}, "", 0)
+	l.SetModuleLevel("db", DEBUG)
+	l.SetModuleLevel("module_test", TRACE)
+	for i := 0; i < 100; i++ {
+		l.With("request", i).Debug("request")
+	}
+	entries := 0
+	l.moduleLevels().cache.Range(func(_, _ interface{}) bool {
+		entries++
+		return true
+	})
+	if entries != 1 {
+		t.Errorf("got %d cached call sites; want 1", entries)
+	}
+}
+
+func TestSetModuleLevelsErrors(t *testing.T) {
+	l := New(&bytes.Buffer{}, "", 0)
+	for _, spec := range []string{"db", "=debug", "db=loud", "[=debug"} {
+		if err := l.SetModuleLevels(spec); err == nil {
+			t.Errorf("spec %q: expected error", spec)
+		}
+	}
+}
+
+func TestPackagePath(t *testing.T) {
+	tests := map[string]string{
+		"github.com/mysqto/log.(*Logger).Info": "github.com/mysqto/log",
+		"main.main":                            "main",
+		"net/http.(*Server).Serve.func1":       "net/http",
+	}
+	for function, want := range tests {
+		if got := packagePath(function); got != want {
+			t.Errorf("%s: got %q; want %q", function, got, want)
+		}
+	}
+}
+
+func BenchmarkModuleLevel(b *testing.B) {
+	l := New(&bytes.Buffer{}, "", 0)
+	l.SetModuleLevel("db", DEBUG)
+	for i := 0; i < b.N; i++ {
+		l.Trace("dropped")
+	}
+}
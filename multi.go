@@ -0,0 +1,200 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Sink is a backend of MultiBackend with its own level, formatter and color setting
+type Sink struct {
+	// Backend receives the records of the sink
+	Backend Backend
+
+	// Level is the most verbose level written to the sink, records of
+	// Print[f|ln] are always written. 0 writes the records of all levels.
+	Level Level
+
+	// Formatter is set as formatter of Backend if not nil
+	Formatter Formatter
+
+	// Color writes the records in color if Backend is a tty, the Lcolor
+	// flag of the logger is not used
+	Color bool
+}
+
+// MultiBackend dispatches each record to several backends, the message of
+// a record is formatted only once for all of them
+type MultiBackend struct {
+	mu    sync.Mutex
+	sinks []Sink // replaced, never modified, by SetFormatter
+}
+
+// NewMultiLogger creates a new logger writing to all sinks, the level of the
+// logger must be at least as verbose as the most verbose sink
+func NewMultiLogger(prefix string, flag int, sinks ...Sink) *Logger {
+	var name string
+
+	if flag&Lloggername > 0 {
+		name = procName()
+	}
+
+	l := &Logger{
+		backend: NewMultiBackend(sinks...),
+		prefix:  prefix,
+		flag:    flag,
+		name:    name,
+		level:   DEBUG,
+	}
+	go l.backend.start()
+	logger.Store(l)
+	return l
+}
+
+// NewMultiBackend creates a backend writing to all sinks, the backends of
+// the sinks are started by the MultiBackend
+func NewMultiBackend(sinks ...Sink) Backend {
+	m := &MultiBackend{sinks: make([]Sink, len(sinks))}
+	copy(m.sinks, sinks)
+	for _, sink := range m.sinks {
+		if sink.Formatter != nil {
+			sink.Backend.SetFormatter(sink.Formatter)
+		}
+	}
+	return m
+}
+
+// Sinks returns the sinks of current backend
+func (l *MultiBackend) Sinks() []Sink {
+	current := l.current()
+	sinks := make([]Sink, len(current))
+	copy(sinks, current)
+	return sinks
+}
+
+// current returns the sinks, the slice must not be modified
+func (l *MultiBackend) current() []Sink {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sinks
+}
+
+// Writer returns an io.Writer duplicating its writes to the writers of all sinks
+func (l *MultiBackend) Writer() io.Writer {
+	sinks := l.current()
+	writers := make([]io.Writer, 0, len(sinks))
+	for _, sink := range sinks {
+		if w := sink.Backend.Writer(); w != nil {
+			writers = append(writers, w)
+		}
+	}
+	return io.MultiWriter(writers...)
+}
+
+// SetWriter is not supported by MultiBackend
+func (l *MultiBackend) SetWriter(io.Writer) {
+	// not supported
+}
+
+// write writes data to all sinks, returning the first error
+func (l *MultiBackend) write(data []byte) error {
+	var first error
+	for _, sink := range l.current() {
+		if err := sink.Backend.write(data); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (l *MultiBackend) log(r *Record) {
+	for _, sink := range l.current() {
+		if sink.Level > none && r.level > sink.Level {
+			continue
+		}
+		flag := r.flag &^ Lcolor
+		if sink.Color {
+			flag |= Lcolor
+		}
+		logSink(sink.Backend, r.clone(flag))
+	}
+}
+
+// logSink hands r to backend, a panicking backend does not stop the other sinks
+func logSink(backend Backend, r *Record) {
+	defer func() {
+		if err := recover(); err != nil {
+			backendError(r, backend, OpWrite, fmt.Errorf("panic: %v", err))
+		}
+	}()
+	backend.log(r)
+}
+
+// start starts the backends of all sinks
+func (l *MultiBackend) start() {
+	for _, sink := range l.current() {
+		go sink.Backend.start()
+	}
+}
+
+// FailedWrites returns the number of writes failed in the backends of all sinks
+func (l *MultiBackend) FailedWrites() uint64 {
+	var failed uint64
+	for _, sink := range l.current() {
+		failed += BackendFailedWrites(sink.Backend)
+	}
+	return failed
+}
+
+// Flush flushes the backends of all sinks, returning the first error
+func (l *MultiBackend) Flush(ctx context.Context) error {
+	var first error
+	for _, sink := range l.current() {
+		if err := sink.Backend.Flush(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Close closes the backends of all sinks, returning the first error
+func (l *MultiBackend) Close(ctx context.Context) error {
+	var first error
+	for _, sink := range l.current() {
+		if err := sink.Backend.Close(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// SetFormatter sets the formatter of the backends of all sinks
+func (l *MultiBackend) SetFormatter(f Formatter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sinks := make([]Sink, len(l.sinks))
+	for i, sink := range l.sinks {
+		sink.Formatter = f
+		sink.Backend.SetFormatter(f)
+		sinks[i] = sink
+	}
+	l.sinks = sinks
+}
+
+// the sinks are formatted by their own backends
+func (l *MultiBackend) formatter() Formatter {
+	return nil
+}
+
+func (l *MultiBackend) isatty() bool {
+	return false
+}
+
+func (l *MultiBackend) fd() Handler {
+	return nil
+}
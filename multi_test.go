@@ -0,0 +1,74 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+type countingStringer struct {
+	calls int
+}
+
+func (s *countingStringer) String() string {
+	s.calls++
+	return "counted"
+}
+
+type panicWriter struct{}
+
+func (panicWriter) Write([]byte) (int, error) {
+	panic("broken writer")
+}
+
+func TestMultiBackend(t *testing.T) {
+	var text, json bytes.Buffer
+	l := NewMultiLogger("", 0,
+		Sink{Backend: NewSyncBackend(panicWriter{})},
+		Sink{Backend: NewSyncBackend(&text), Level: INFO},
+		Sink{Backend: NewSyncBackend(&json), Formatter: &JSONFormatter{}},
+	)
+	s := &countingStringer{}
+	l.Debugf("debug %v", s)
+	l.Info("info")
+
+	if s.calls != 1 {
+		t.Errorf("message formatted %d times; want 1", s.calls)
+	}
+	if want := "[ INFO] info\n"; text.String() != want {
+		t.Errorf("text sink got %q; want %q", text.String(), want)
+	}
+	want := "{\"level\":\"DEBUG\",\"msg\":\"debug counted\"}\n{\"level\":\"INFO\",\"msg\":\"info\"}\n"
+	if json.String() != want {
+		t.Errorf("json sink got %q; want %q", json.String(), want)
+	}
+}
+
+func TestMultiBackendSetFormatter(t *testing.T) {
+	m := NewMultiBackend(Sink{Backend: NewSyncBackend(ioutil.Discard)})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			m.SetFormatter(&JSONFormatter{})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if _, err := m.Writer().Write([]byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.write([]byte("line\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+	if _, ok := m.(*MultiBackend).Sinks()[0].Formatter.(*JSONFormatter); !ok {
+		t.Error("formatter not set")
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"sync"
+	"time"
+)
+
+// OverflowPolicy decides what happens to a record logged to a full queue
+type OverflowPolicy int
+
+// overflow policies
+const (
+	OverflowBlock      OverflowPolicy = iota // waits until the queue has room
+	OverflowDropNewest                       // drops the record being logged
+	OverflowDropOldest                       // drops the oldest droppable record in the queue
+	OverflowTimeout                          // waits up to a timeout, then drops the record being logged
+)
+
+// recordQueue is a bounded FIFO of records between the loggers and the
+// writing goroutine of a backend
+type recordQueue struct {
+	mu       sync.Mutex
+	notEmpty sync.Cond
+	notFull  sync.Cond
+	buf      []*Record // ring buffer of the queued records
+	head     int       // index of the oldest record in buf
+	n        int       // number of queued records
+	closed   bool
+	last     *Record // last record put except flush markers, nil if none
+	waiting  int     // number of puts waiting for room
+
+	policy  OverflowPolicy
+	timeout time.Duration // wait time of OverflowTimeout
+	keep    Level         // records of this level or more severe are never dropped
+}
+
+func newRecordQueue(capacity int, policy OverflowPolicy, timeout time.Duration, keep Level) *recordQueue {
+	q := &recordQueue{
+		buf:     make([]*Record, capacity),
+		policy:  policy,
+		timeout: timeout,
+		keep:    keep,
+	}
+	q.notEmpty.L = &q.mu
+	q.notFull.L = &q.mu
+	return q
+}
+
+// droppable reports whether r may be dropped, records of Print[f|ln] are
+// droppable, flush markers and kept records are not
+func (q *recordQueue) droppable(r *Record) bool {
+	return r.flushed == nil && !r.kept && (r.level == none || r.level > q.keep)
+}
+
+// put queues r applying the overflow policy if the queue is full and
+// returns the number of records dropped, records put after close are
+// discarded
+func (q *recordQueue) put(r *Record) int {
+	return q.putUntil(r, nil)
+}
+
+// putUntil is put giving up waiting for room when done is closed, r is
+// discarded then. Kept records wait for room instead of evicting records.
+func (q *recordQueue) putUntil(r *Record, done <-chan struct{}) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if r.flushed == nil {
+		q.last = r
+	}
+	var deadline time.Time
+	watching := false
+	for !q.closed && q.n == len(q.buf) {
+		if done != nil && !watching {
+			watching = true
+			stop := make(chan struct{})
+			defer close(stop)
+			go func() {
+				select {
+				case <-done:
+					q.mu.Lock()
+					defer q.mu.Unlock()
+					q.notFull.Broadcast()
+				case <-stop:
+				}
+			}()
+		}
+		select {
+		case <-done:
+			return 0
+		default:
+		}
+		droppable := q.droppable(r)
+		switch {
+		case q.policy == OverflowDropOldest && !r.kept:
+			if i := q.oldest(); i >= 0 {
+				q.remove(i)
+				q.push(r)
+				return 1
+			}
+			if droppable {
+				return 1
+			}
+		case q.policy == OverflowDropNewest && droppable:
+			return 1
+		case q.policy == OverflowTimeout && droppable:
+			if deadline.IsZero() {
+				deadline = time.Now().Add(q.timeout)
+				timer := time.AfterFunc(q.timeout, func() {
+					q.mu.Lock()
+					defer q.mu.Unlock()
+					q.notFull.Broadcast()
+				})
+				defer timer.Stop()
+			} else if !time.Now().Before(deadline) {
+				return 1
+			}
+		}
+		q.waiting++
+		q.notFull.Wait()
+		q.waiting--
+	}
+	select {
+	case <-done:
+		return 0
+	default:
+	}
+	if !q.closed {
+		q.push(r)
+	}
+	return 0
+}
+
+// takeAll appends all queued records to dst, waiting until one is queued
+// or deadline passes if deadline is not zero. ok is false if the queue is
+// closed and empty.
+func (q *recordQueue) takeAll(dst []*Record, deadline time.Time) (records []*Record, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.n == 0 && !q.closed && !deadline.IsZero() {
+		timer := time.AfterFunc(time.Until(deadline), func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.notEmpty.Broadcast()
+		})
+		defer timer.Stop()
+	}
+	for q.n == 0 && !q.closed && (deadline.IsZero() || time.Now().Before(deadline)) {
+		q.notEmpty.Wait()
+	}
+	for q.n > 0 {
+		dst = append(dst, q.buf[q.head])
+		q.remove(0)
+	}
+	q.notFull.Broadcast()
+	return dst, len(dst) > 0 || !q.closed
+}
+
+// drain passes the records of q to add until q is closed, flush is
+// called at the end of each batch. A batch ends when add returns true,
+// no record is queued or linger has passed since its first record.
+func (q *recordQueue) drain(linger func() time.Duration, add func(r *Record) (full bool), flush func()) {
+	var records []*Record
+	for {
+		var ok bool
+		if records, ok = q.takeAll(records[:0], time.Time{}); !ok {
+			return
+		}
+		wait := linger()
+		deadline := time.Now().Add(wait)
+		for len(records) > 0 {
+			full := false
+			for i, r := range records {
+				full = add(r) || full
+				records[i] = nil
+			}
+			if full || wait <= 0 || !time.Now().Before(deadline) {
+				break
+			}
+			records, _ = q.takeAll(records[:0], deadline)
+		}
+		flush()
+	}
+}
+
+// close wakes up all waiting goroutines, takeAll returns the queued
+// records before reporting the queue closed
+func (q *recordQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// waiters returns the number of puts waiting for room
+func (q *recordQueue) waiters() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.waiting
+}
+
+// lastRecord returns the last record put, nil if none
+func (q *recordQueue) lastRecord() *Record {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.last
+}
+
+// push appends r to the queue, the caller holds q.mu and the queue is not full
+func (q *recordQueue) push(r *Record) {
+	q.buf[(q.head+q.n)%len(q.buf)] = r
+	q.n++
+	q.notEmpty.Signal()
+}
+
+// oldest returns the position of the oldest droppable record, -1 if there is none
+func (q *recordQueue) oldest() int {
+	for i := 0; i < q.n; i++ {
+		if q.droppable(q.buf[(q.head+i)%len(q.buf)]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// remove removes the record at position i moving the older records forward
+func (q *recordQueue) remove(i int) {
+	for ; i > 0; i-- {
+		q.buf[(q.head+i)%len(q.buf)] = q.buf[(q.head+i-1)%len(q.buf)]
+	}
+	q.buf[q.head] = nil
+	q.head = (q.head + 1) % len(q.buf)
+	q.n--
+}
@@ -6,7 +6,7 @@ package log
 
 import (
 	"fmt"
-	"sync/atomic"
+	"sync"
 	"time"
 )
 
@@ -23,24 +23,30 @@ const (
 // was created, an increasing id, filename and line and finally the actual
 // formatted log line.
 type Record struct {
-	// index and formatted are the 1st two field to keep memory aligned since on 32-bit machine
+	// index is the 1st field to keep memory aligned since on 32-bit machine
 	// if the atomic value is not aligned, cmpxchg will cause coredump
-	index     uint64 // current log index
-	formatted uint32 // flag to identify log buffer is formatted
-	time      time.Time
-	prefix    *string
-	module    *string
-	level     Level
-	file      *string
-	line      int
-	function  *string
-	fmt       *string
-	args      []interface{}
-	buf       []byte
-	flag      int
-	mode      writeMode
-	color     string
-	newline   bool
+	index      uint64 // current log index
+	time       time.Time
+	prefix     *string
+	module     *string
+	level      Level
+	file       *string
+	line       int
+	function   *string
+	pc         uintptr // program counter of the caller, 0 if unknown
+	fmt        *string
+	args       []interface{}
+	fields     []Field
+	stack      []string // call stack of PANIC records and of Lstacktrace records at the stack level
+	flag       int
+	mode       writeMode
+	formatter  Formatter
+	timeFormat TimeFormat
+	msg        string
+	msgOnce    sync.Once   // formats msg only once for all backends
+	onError    func(error) // error handler of the logger, nil for the default one
+	flushed    chan error  // not nil for a flush marker, receives the result of the flush
+	kept       bool        // never dropped by nor evicting from a full queue, such as flush markers
 }
 
 func itoa(buf *[]byte, i, wid int) {
@@ -86,10 +92,10 @@ func (r *Record) formatHeader(buf *[]byte) {
 	if r.prefix != nil {
 		*buf = append(*buf, *r.prefix...)
 	}
-	if r.flag&(Ldate|Ltime|Lmicroseconds) != 0 {
-		if r.flag&LUTC != 0 {
-			r.time = r.time.UTC()
-		}
+	if r.flag&(Ldate|Ltime|Lmicroseconds) != 0 && r.timeFormat != TimeDefault {
+		*buf = appendTime(*buf, r.time, r.timeFormat)
+		*buf = append(*buf, ' ')
+	} else if r.flag&(Ldate|Ltime|Lmicroseconds) != 0 {
 		if r.flag&Ldate != 0 {
 			year, month, day := r.time.Date()
 			itoa(buf, year, 4)
@@ -116,19 +122,23 @@ func (r *Record) formatHeader(buf *[]byte) {
 
 	if r.flag&Lsequence != 0 {
 		*buf = append(*buf, '[')
-		utoa(buf, atomic.LoadUint64(&r.index), 10)
+		utoa(buf, r.index, 10)
 		*buf = append(*buf, "] "...)
 	}
 
 	// none is for Print/Printf/Println
 	if r.level > none {
 		*buf = append(*buf, '[')
-		*buf = append(*buf, levels[r.level]...)
+		name := levelString(r.level)
+		for i := len(name); i < 5; i++ {
+			*buf = append(*buf, ' ')
+		}
+		*buf = append(*buf, name...)
 		*buf = append(*buf, "] "...)
 	}
 
-	if r.flag&Lloggername > 0 && r.module != nil && len(*r.module) > 0 {
-		*buf = append(*buf, *r.module...)
+	if r.flag&Lloggername > 0 && len(r.Name()) > 0 {
+		*buf = append(*buf, r.Name()...)
 
 		if r.flag&Lgoroutineid != 0 {
 			*buf = append(*buf, '-')
@@ -138,10 +148,11 @@ func (r *Record) formatHeader(buf *[]byte) {
 	}
 
 	if r.flag&(Lshortfile|Llongfile) != 0 {
+		file := r.File()
 		if r.flag&Lshortfile != 0 {
-			*r.file = splitLast(*r.file, '/')
+			file = splitLast(file, '/')
 		}
-		*buf = append(*buf, *r.file...)
+		*buf = append(*buf, file...)
 		*buf = append(*buf, ':')
 		itoa(buf, r.line, -1)
 		*buf = append(*buf, ':')
@@ -150,10 +161,11 @@ func (r *Record) formatHeader(buf *[]byte) {
 		}
 	}
 	if r.flag&(Lshortfunc|Llongfunc) != 0 {
+		function := r.Function()
 		if r.flag&Lshortfunc != 0 {
-			*r.function = splitLast(*r.function, '.')
+			function = splitLast(function, '.')
 		}
-		*buf = append(*buf, *r.function...)
+		*buf = append(*buf, function...)
 		*buf = append(*buf, ": "...)
 	}
 }
@@ -172,27 +184,200 @@ func (r *Record) print() string {
 	}
 }
 
-// msgBuf format the message into Record.buf
-func (r *Record) msgBuf() []byte {
-	if atomic.LoadUint32(&r.formatted) == 0 {
-		r.buf = r.buf[:0]
-		if len(r.color) > 0 {
-			r.buf = append(r.buf, r.color...)
-		}
-		r.formatHeader(&r.buf)
-		message := r.print()
-		r.buf = append(r.buf, message...)
-		if r.newline && (len(message) == 0 || message[len(message)-1] != '\n') {
-			r.buf = append(r.buf, '\n')
-		}
-		if len(r.color) > 0 {
-			r.buf = append(r.buf, colorReset...)
-		}
-		atomic.StoreUint32(&r.formatted, 1)
+// formatterFor returns the formatter of backend, if backend has no
+// formatter the formatter of the logger is used
+func (r *Record) formatterFor(backend Backend) Formatter {
+	f := backend.formatter()
+	if f == nil {
+		f = r.formatter
+	}
+	if f == nil {
+		f = defaultFormatter
 	}
-	return r.buf
+	return f
+}
+
+// format appends the record formatted for backend to buf
+func (r *Record) format(backend Backend, buf []byte) []byte {
+	return r.formatterFor(backend).Format(r, buf)
+}
+
+// colored reports whether the record is written to backend in color, the
+// output of structured formatters is never colored
+func (r *Record) colored(backend Backend) bool {
+	if r.flag&Lcolor == 0 {
+		return false
+	}
+	switch r.formatterFor(backend).(type) {
+	case *JSONFormatter, *LogfmtFormatter:
+		return false
+	}
+	return true
+}
+
+// appendTo appends the record formatted for backend to buf, wrapped by
+// the color sequence if color is not empty
+func (r *Record) appendTo(backend Backend, buf []byte, color string) []byte {
+	if len(color) > 0 {
+		buf = append(buf, color...)
+	}
+	buf = r.format(backend, buf)
+	if len(color) > 0 {
+		buf = append(buf, colorReset...)
+	}
+	return buf
+}
+
+// ansiColor returns the ANSI color sequence of the record written to
+// backend, empty if the record is not colored or backend is not a tty
+func (r *Record) ansiColor(backend Backend) string {
+	if r.colored(backend) && backend.isatty() {
+		return levelColorSeq(r.level, false)
+	}
+	return ""
+}
+
+// writeTo formats the record into a pooled buffer and writes it to backend,
+// wrapped by the color sequence if color is not empty
+func (r *Record) writeTo(backend Backend, color string) {
+	buf := getBuffer()
+	*buf = r.appendTo(backend, *buf, color)
+	if err := backend.write(*buf); err != nil {
+		backendError(r, backend, OpWrite, err)
+	}
+	putBuffer(buf)
+}
+
+// clone returns a copy of r with the given flag sharing the formatted message of r
+func (r *Record) clone(flag int) *Record {
+	c := &Record{
+		index:      r.index,
+		time:       r.time,
+		prefix:     r.prefix,
+		module:     r.module,
+		level:      r.level,
+		file:       r.file,
+		line:       r.line,
+		function:   r.function,
+		pc:         r.pc,
+		fields:     r.fields,
+		stack:      r.stack,
+		flag:       flag,
+		formatter:  r.formatter,
+		timeFormat: r.timeFormat,
+		msg:        r.Message(),
+		onError:    r.onError,
+	}
+	c.msgOnce.Do(func() {})
+	return c
+}
+
+// derive returns a record at level with the message msg written with the
+// settings of the logger of r, the caller is not written
+func (r *Record) derive(level Level, msg string) *Record {
+	d := &Record{
+		index:      r.index,
+		time:       time.Now(),
+		prefix:     r.prefix,
+		module:     r.module,
+		level:      level,
+		flag:       r.flag &^ (Lshortfile | Llongfile | Lshortfunc | Llongfunc),
+		formatter:  r.formatter,
+		timeFormat: r.timeFormat,
+		msg:        msg,
+		onError:    r.onError,
+	}
+	d.msgOnce.Do(func() {})
+	return d
+}
+
+// Time returns the time the record was created
+func (r *Record) Time() time.Time {
+	return r.time
+}
+
+// Level returns the level of the record, 0 for records written by Print[f|ln]
+func (r *Record) Level() Level {
+	return r.level
+}
+
+// TimeFormat returns the time format of the logger writing the record
+func (r *Record) TimeFormat() TimeFormat {
+	return r.timeFormat
+}
+
+// Index returns the sequence number of the record
+func (r *Record) Index() uint64 {
+	return r.index
+}
+
+// Prefix returns the prefix of the logger writing the record
+func (r *Record) Prefix() string {
+	if r.prefix == nil {
+		return ""
+	}
+	return *r.prefix
+}
+
+// Name returns the name of the logger writing the record
+func (r *Record) Name() string {
+	if r.module == nil {
+		return ""
+	}
+	return *r.module
+}
+
+// File returns the full file name of the caller, empty if none of
+// Lshortfile, Llongfile, Lshortfunc or Llongfunc is set
+func (r *Record) File() string {
+	if r.file == nil {
+		return ""
+	}
+	return *r.file
+}
+
+// Line returns the line number of the caller
+func (r *Record) Line() int {
+	return r.line
+}
+
+// Function returns the full function name of the caller
+func (r *Record) Function() string {
+	if r.function == nil {
+		return ""
+	}
+	return *r.function
+}
+
+// Flags returns the output flags of the logger writing the record
+func (r *Record) Flags() int {
+	return r.flag
+}
+
+// Message returns the formatted log message without trailing newline
+func (r *Record) Message() string {
+	r.msgOnce.Do(func() {
+		r.msg = r.print()
+		if len(r.msg) > 0 && r.msg[len(r.msg)-1] == '\n' {
+			r.msg = r.msg[:len(r.msg)-1]
+		}
+	})
+	return r.msg
+}
+
+// Fields returns the key/value pairs attached to the record
+func (r *Record) Fields() []Field {
+	return r.fields
+}
+
+// Stack returns the call stack captured for the record, one frame per
+// entry with the function name followed by file:line
+func (r *Record) Stack() []string {
+	return r.stack
 }
 
-func (r *Record) message() string {
-	return string(r.msgBuf())
+// AddFields attaches the given key/value pairs to the record like Logger.With,
+// it is used by hooks to add fields before the record is written
+func (r *Record) AddFields(keyvals ...interface{}) {
+	r.fields = joinFields(r.fields, makeFields(keyvals))
 }
@@ -0,0 +1,103 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Recover logs a recovered panic with the stack of the panicking goroutine
+// at ERROR to l, the std logger if l is nil, and lets the goroutine return
+// normally. It must be deferred directly:
+//
+//	defer log.Recover(l)
+func Recover(l *Logger) {
+	if e := recover(); e != nil {
+		l.recovered(e, ERROR)
+	}
+}
+
+// RecoverFatal logs a recovered panic like Recover at FATAL and exits the
+// process like Fatal
+func RecoverFatal(l *Logger) {
+	if e := recover(); e != nil {
+		l.recovered(e, FATAL)
+	}
+}
+
+// RecoverRepanic logs a recovered panic like Recover, waits until the
+// record is written and panics again with the recovered value
+func RecoverRepanic(l *Logger) {
+	if e := recover(); e != nil {
+		l.recovered(e, ERROR)
+		panic(e)
+	}
+}
+
+// recovered writes the panic value e at level with the stack of the
+// panicking goroutine, the caller of the panicking function is the caller
+// of the record. It is called by the deferred Recover functions.
+func (l *Logger) recovered(e interface{}, level Level) {
+	if l == nil {
+		l = std()
+	}
+	if level == FATAL {
+		// a recovered panic never continues as if nothing happened
+		defer l.fatal()
+	}
+	frames := panicFrames(callers(2))
+	var pc uintptr
+	if len(frames) > 0 {
+		pc = frames[0].PC
+	}
+	if !l.enabledPC(level, pc) {
+		return
+	}
+
+	format := "panic: %v"
+	r := &Record{
+		time:  time.Now(),
+		level: level,
+		pc:    pc,
+		fmt:   &format,
+		args:  []interface{}{e},
+		stack: formatStack(frames),
+		mode:  writeModeLogf,
+	}
+	func() {
+		// unlocked even if a hook or the backend panics
+		l.lock()
+		defer l.unlock()
+		if l.flag&(Lshortfile|Llongfile|Lshortfunc|Llongfunc) != 0 {
+			file, function := "???", "???"
+			if len(frames) > 0 {
+				file, function, r.line = frames[0].File, frames[0].Function, frames[0].Line
+			}
+			r.file, r.function = &file, &function
+		}
+		l.emit(r)
+	}()
+	if level != FATAL {
+		l.flushPending("panic")
+	}
+}
+
+// panicFrames returns the frames of the panicking goroutine from the frames
+// of a deferred call, the frames up to runtime.gopanic belong to the
+// deferred call and the runtime frames after it raise runtime errors
+func panicFrames(frames []runtime.Frame) []runtime.Frame {
+	for i, frame := range frames {
+		if frame.Function == "runtime.gopanic" {
+			frames = frames[i+1:]
+			break
+		}
+	}
+	for len(frames) > 0 && strings.HasPrefix(frames[0].Function, "runtime.") {
+		frames = frames[1:]
+	}
+	return frames
+}
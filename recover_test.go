@@ -0,0 +1,112 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPanic(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Lshortfile)
+	func() {
+		defer func() {
+			if e := recover(); e != "boom 1" {
+				t.Errorf("recovered %v; want boom 1", e)
+			}
+		}()
+		l.Panicf("boom %d", 1)
+	}()
+
+	lines := strings.Split(b.String(), "\n")
+	if want := "[PANIC] recover_test.go:24: boom 1"; lines[0] != want {
+		t.Errorf("got %q; want %q", lines[0], want)
+	}
+	if len(lines) < 3 || !strings.HasPrefix(lines[1], "\tgithub.com/mysqto/log.TestPanic.func1 ") ||
+		!strings.HasSuffix(lines[1], "recover_test.go:24") {
+		t.Errorf("got stack %q", lines[1:])
+	}
+}
+
+func panicking(m map[string]int) {
+	m["k"] = 1
+}
+
+func TestRecover(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Lshortfile|Lshortfunc)
+	l.SetFormatter(&JSONFormatter{})
+	func() {
+		defer Recover(l)
+		panicking(nil)
+	}()
+
+	var got struct {
+		Level  string   `json:"level"`
+		Caller string   `json:"caller"`
+		Func   string   `json:"func"`
+		Msg    string   `json:"msg"`
+		Stack  []string `json:"stack"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("%v: %q", err, b.String())
+	}
+	if got.Level != "ERROR" || got.Caller != "recover_test.go:38" || got.Func != "panicking" ||
+		got.Msg != "panic: assignment to entry in nil map" {
+		t.Errorf("got %+v", got)
+	}
+	if len(got.Stack) < 2 || !strings.HasPrefix(got.Stack[0], "github.com/mysqto/log.panicking ") ||
+		!strings.HasPrefix(got.Stack[1], "github.com/mysqto/log.TestRecover.func1 ") {
+		t.Errorf("got stack %q", got.Stack)
+	}
+}
+
+func TestRecoverRepanic(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	defer func() {
+		if e := recover(); e != "boom" {
+			t.Errorf("recovered %v; want boom", e)
+		}
+		if !strings.HasPrefix(b.String(), "[ERROR] panic: boom\n\t") {
+			t.Errorf("got %q", b.String())
+		}
+	}()
+	defer RecoverRepanic(l)
+	panic("boom")
+}
+
+func TestRecoverPanickingBackend(t *testing.T) {
+	l := New(panicWriter{}, "", 0)
+	func() {
+		defer func() {
+			if e := recover(); e != "broken writer" {
+				t.Errorf("recovered %v; want broken writer", e)
+			}
+		}()
+		defer Recover(l)
+		panic("boom")
+	}()
+
+	var b bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		l.SetOutput(&b)
+		l.Info("unlocked")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logger left locked by a panicking backend")
+	}
+	if want := "[ INFO] unlocked\n"; b.String() != want {
+		t.Errorf("got %q; want %q", b.String(), want)
+	}
+}
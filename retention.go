@@ -0,0 +1,158 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// limits of the retention policy reported to RotateOptions.OnDelete
+const (
+	DeleteMaxAge       = "max age"
+	DeleteMaxTotalSize = "max total size"
+	DeleteMinFreeSpace = "min free space"
+)
+
+// rotatedFile is a rotated file considered by the retention policy
+type rotatedFile struct {
+	name    string
+	size    ByteSize
+	modTime time.Time
+}
+
+// rotatedFiles returns the rotated files of l from the oldest to the newest,
+// the archives being written and the files waiting for compression are
+// returned if temp is true
+func (l *RotateLogger) rotatedFiles(temp bool) ([]rotatedFile, error) {
+	dir, base := filepath.Split(l.filename)
+	if len(dir) == 0 {
+		dir = "."
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []rotatedFile
+	for _, info := range infos {
+		if !temp && (strings.HasSuffix(info.Name(), archiveSuffix) || l.isArchiving(filepath.Join(dir, info.Name()))) {
+			continue
+		}
+		if info.Mode().IsRegular() && rotatedName(base, info.Name()) {
+			files = append(files, rotatedFile{
+				name:    filepath.Join(dir, info.Name()),
+				size:    ByteSize(info.Size()),
+				modTime: info.ModTime(),
+			})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].modTime.Equal(files[j].modTime) {
+			return files[i].modTime.Before(files[j].modTime)
+		}
+		return files[i].name < files[j].name
+	})
+	return files, nil
+}
+
+// rotatedName reports whether name is a name given by the rotation to the
+// files of base: base.<n> or base.<period>[.<n>], followed by an optional
+// compress suffix and the suffix of the archives being written
+func rotatedName(base, name string) bool {
+	if !strings.HasPrefix(name, base+".") {
+		return false
+	}
+	rest := strings.TrimSuffix(name[len(base)+1:], archiveSuffix)
+	for _, c := range []CompressMethod{GZIP, Zlib, LZW} {
+		if strings.HasSuffix(rest, c.String()) {
+			rest = strings.TrimSuffix(rest, c.String())
+			break
+		}
+	}
+	if isNumber(rest) {
+		return true
+	}
+	// the periods are named 2006-01-02 or 2006-01-02T15
+	const day, hour = len("2006-01-02"), len("2006-01-02T15")
+	if len(rest) < day {
+		return false
+	}
+	if _, err := time.Parse("2006-01-02", rest[:day]); err != nil {
+		return false
+	}
+	rest = rest[day:]
+	if len(rest) >= hour-day && rest[0] == 'T' && isNumber(rest[1:hour-day]) {
+		rest = rest[hour-day:]
+	}
+	return len(rest) == 0 || rest[0] == '.' && isNumber(rest[1:])
+}
+
+// isNumber reports whether s is a non empty string of decimal digits
+func isNumber(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// retain deletes the oldest rotated files until the limits of the
+// retention policy are met, r is the record causing the rotation or nil
+func (l *RotateLogger) retain(r *Record) {
+	if l.maxAge <= 0 && l.maxTotalSize <= 0 && l.minFreeSpace <= 0 {
+		return
+	}
+	files, err := l.rotatedFiles(false)
+	if err != nil {
+		backendError(r, l, OpRetain, err)
+		return
+	}
+	var total ByteSize
+	for _, f := range files {
+		total += f.size
+	}
+	now := l.clock.Now()
+	for ; len(files) > 0; files = files[1:] {
+		f := files[0]
+		var reason string
+		switch {
+		case l.maxAge > 0 && now.Sub(f.modTime) > l.maxAge:
+			reason = DeleteMaxAge
+		case l.maxTotalSize > 0 && total > l.maxTotalSize:
+			reason = DeleteMaxTotalSize
+		case l.minFreeSpace > 0 && l.lowSpace(r):
+			reason = DeleteMinFreeSpace
+		default:
+			return
+		}
+		if err := os.Remove(f.name); err != nil {
+			backendError(r, l, OpRetain, err)
+			return
+		}
+		total -= f.size
+		if l.onDelete != nil {
+			l.onDelete(f.name, reason)
+		}
+	}
+}
+
+// lowSpace reports whether the free space of the file system of the log
+// is less than minFreeSpace, false if it is unknown
+func (l *RotateLogger) lowSpace(r *Record) bool {
+	free, err := diskFree(filepath.Dir(l.filename))
+	if err != nil {
+		backendError(r, l, OpRetain, err)
+		return false
+	}
+	return free < l.minFreeSpace
+}
@@ -5,16 +5,13 @@
 package log
 
 import (
-	"bufio"
-	"compress/gzip"
-	"compress/lzw"
-	"compress/zlib"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
-	"sync/atomic"
+	"time"
 )
 
 // ByteSize represent file ByteSize in byte
@@ -63,7 +60,7 @@ func (c CompressMethod) String() string {
 
 // RotateLogger represents an log backend supporting log rotating and compress
 type RotateLogger struct {
-	stopped     uint32
+	writeFailures
 	maxFiles    int
 	maxSize     ByteSize
 	writtenSize ByteSize
@@ -71,9 +68,93 @@ type RotateLogger struct {
 	fileIndex   int
 	out         io.WriteCloser
 	mu          sync.Mutex
-	queue       chan *Record
+	queue       *recordQueue
 	stop        chan struct{} // Notify closing
 	compress    CompressMethod
+	format      Formatter
+	buf         []byte        // records formatted by the writing goroutine
+	last        *Record       // last record in buf
+	maxBatch    int           // size of buf written at once
+	linger      time.Duration // time to wait for more records before writing buf
+
+	schedule rotateSchedule
+	clock    Clock
+	period   time.Time // start of the period of the current file
+	next     time.Time // start of the next period, zero without schedule
+
+	maxAge       time.Duration
+	maxTotalSize ByteSize
+	minFreeSpace ByteSize
+	onDelete     func(name, reason string)
+
+	archives  chan *archiveJob       // rotated files waiting for compression
+	archiving sync.WaitGroup         // rotated files queued or being compressed
+	pendingMu sync.Mutex             // protects pending and the names of its jobs
+	pending   map[string]*archiveJob // rotated files queued or being compressed by name
+}
+
+// RotateOptions configures the rotation of a rotate backend
+type RotateOptions struct {
+	// Filename is the file written by NewRotateLoggerWithOptions, the
+	// process name with the .log extension in the working directory if
+	// empty. NewRotateBackendWithOptions writes to its filename argument.
+	Filename string
+
+	// MaxSize rotates the file before a write makes it larger than
+	// MaxSize, 0 disables the size based rotation
+	MaxSize ByteSize
+
+	// MaxFiles is the number of rotated files kept without Period, the
+	// files are numbered from the newest one: app.log.0, app.log.1...
+	MaxFiles int
+
+	// Compress is the compress method of the rotated files, they are
+	// compressed in the background once renamed. The errors of the
+	// compression are reported to the default error handler.
+	Compress CompressMethod
+
+	// Period rotates the file at the start of each period, the rotated
+	// files are named by the period they cover: app.log.2026-10-17 for
+	// Daily or Weekly and app.log.2026-10-17T15 for Hourly. The files
+	// rotated by size within a period get an increasing number:
+	// app.log.2026-10-17.1, app.log.2026-10-17.2...
+	Period RotatePeriod
+
+	// At is the wall-clock time of Daily and Weekly rotation as offset
+	// from midnight, e.g. 2*time.Hour rotates at 02:00
+	At time.Duration
+
+	// Weekday is the day of Weekly rotation
+	Weekday time.Weekday
+
+	// Location is the time zone of the schedule and the period names,
+	// time.Local if nil
+	Location *time.Location
+
+	// Clock is the source of time of the schedule and of MaxAge, the
+	// system clock if nil
+	Clock Clock
+
+	// MaxAge deletes the rotated files last written more than MaxAge ago,
+	// 0 keeps them regardless of their age
+	MaxAge time.Duration
+
+	// MaxTotalSize deletes the oldest rotated files while all of them take
+	// more than MaxTotalSize, 0 disables the limit
+	MaxTotalSize ByteSize
+
+	// MinFreeSpace deletes the oldest rotated files while the free space of
+	// the file system of the log is less than MinFreeSpace, 0 disables the
+	// limit
+	MinFreeSpace ByteSize
+
+	// OnDelete is called with the name of each rotated file deleted by
+	// MaxAge, MaxTotalSize or MinFreeSpace and the limit causing the
+	// deletion: DeleteMaxAge, DeleteMaxTotalSize or DeleteMinFreeSpace.
+	// It is called by the writing goroutine so it must not log to the
+	// logger of the backend, the failed deletions are reported to the
+	// error handler.
+	OnDelete func(name, reason string)
 }
 
 // NewRotateLogger creates a rotate logger with given log level and flags
@@ -97,22 +178,74 @@ func NewRotateLogger(level Level, prefix string, maxSize ByteSize, compress Comp
 	return l
 }
 
+// NewRotateLoggerWithOptions creates a rotate logger with given log level,
+// flags and rotation options
+func NewRotateLoggerWithOptions(level Level, prefix string, flag int, opts RotateOptions) *Logger {
+
+	name := procName()
+	filename := opts.Filename
+	if len(filename) == 0 {
+		filename = fmt.Sprintf("%s.log", name)
+	}
+
+	l := &Logger{
+		level:   level,
+		mu:      sync.Mutex{},
+		prefix:  prefix,
+		flag:    flag,
+		backend: NewRotateBackendWithOptions(filename, opts),
+		index:   0,
+		name:    name,
+	}
+	go l.backend.start()
+
+	logger.Store(l)
+
+	return l
+}
+
 // NewRotateBackend creates a rotate logger backend with given parameters
 func NewRotateBackend(filename string, maxFiles int, maxSize ByteSize, compress CompressMethod) Backend {
+	return NewRotateBackendWithOptions(filename, RotateOptions{
+		MaxSize:  maxSize,
+		MaxFiles: maxFiles,
+		Compress: compress,
+	})
+}
+
+// NewRotateBackendWithOptions creates a rotate logger backend writing to
+// filename, rotated by size, by period or both
+func NewRotateBackendWithOptions(filename string, opts RotateOptions) Backend {
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = 32
+	}
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
 
 	backend := &RotateLogger{
-		maxFiles:    maxFiles,
-		maxSize:     maxSize,
+		maxFiles:    opts.MaxFiles,
+		maxSize:     opts.MaxSize,
 		writtenSize: 0,
-		filename:    filename,
+		filename:    filepath.Clean(filename),
 		mu:          sync.Mutex{},
-		queue:       make(chan *Record),
+		queue:       newRecordQueue(1024, OverflowBlock, 0, ERROR),
 		stop:        make(chan struct{}),
-		stopped:     0,
-		compress:    compress,
+		compress:    opts.Compress,
+		maxBatch:    defaultBatchBytes,
+		schedule:    newRotateSchedule(opts),
+		clock:       opts.Clock,
+
+		maxAge:       opts.MaxAge,
+		maxTotalSize: opts.MaxTotalSize,
+		minFreeSpace: opts.MinFreeSpace,
+		onDelete:     opts.OnDelete,
+		archives:     make(chan *archiveJob, maxPendingArchives),
+		pending:      make(map[string]*archiveJob),
 	}
 
-	backend.rotate()
+	go backend.archiver(backend.recoverArchives())
+	backend.rotate(nil)
 
 	return backend
 }
@@ -136,18 +269,37 @@ func (l *RotateLogger) SetWriter(w io.Writer) {
 	}
 }
 
-// Flush the current log backend
-func (l *RotateLogger) Flush() {
-	atomic.StoreUint32(&l.stopped, 1)
-	close(l.queue)
-	<-l.stop
-	closeBackend(l)
+// Flush waits until the records queued so far are written and synced
+func (l *RotateLogger) Flush(ctx context.Context) error {
+	return flushQueue(ctx, l.queue, l.stop)
 }
 
-func (l *RotateLogger) log(r *Record) {
-	if atomic.LoadUint32(&l.stopped) == 0 {
-		l.queue <- r
+// Close writes the queued records, closes the file, waits until the
+// rotated files are compressed and stops current backend
+func (l *RotateLogger) Close(ctx context.Context) error {
+	l.queue.close()
+	return waitStop(ctx, l.stop)
+}
+
+// SetBatch sets the size of the formatted records written at once and the
+// time to wait for more records before writing them, 64KB and 0 by default
+func (l *RotateLogger) SetBatch(maxBytes int, linger time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if maxBytes <= 0 {
+		maxBytes = defaultBatchBytes
 	}
+	l.maxBatch, l.linger = maxBytes, linger
+}
+
+func (l *RotateLogger) batchOptions() (int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.maxBatch, l.linger
+}
+
+func (l *RotateLogger) log(r *Record) {
+	l.queue.put(r)
 }
 
 func (l *RotateLogger) write(data []byte) error {
@@ -160,13 +312,31 @@ func (l *RotateLogger) write(data []byte) error {
 }
 
 func (l *RotateLogger) start() {
-	l.rotate()
-	for r := range l.queue {
-		l.writeLog(r)
+	l.rotate(nil)
+	linger := func() time.Duration {
+		_, linger := l.batchOptions()
+		return linger
 	}
+	l.queue.drain(linger, l.add, l.flushBatch)
+	closeBackend(l)
+	close(l.archives)
+	l.archiving.Wait()
 	close(l.stop)
 }
 
+// SetFormatter sets the formatter of current backend
+func (l *RotateLogger) SetFormatter(f Formatter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = f
+}
+
+func (l *RotateLogger) formatter() Formatter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.format
+}
+
 // since rotating logging always are files(not console or tty) false
 func (l *RotateLogger) isatty() bool {
 	return false
@@ -179,39 +349,124 @@ func (l *RotateLogger) fd() Handler {
 	return nil
 }
 
-func (l *RotateLogger) writeLog(r *Record) {
-	buf := r.msgBuf()
-	size := ByteSize(len(buf))
+// add formats r into the batch, the batch is written if it is full or
+// the file is rotated before r
+func (l *RotateLogger) add(r *Record) (full bool) {
+	if r.flushed != nil {
+		l.flushBatch()
+		r.flushed <- syncWriter(l.out)
+		return true
+	}
+	if !l.next.IsZero() && !l.clock.Now().Before(l.next) {
+		// the records before r belong to the previous period
+		l.flushBatch()
+		l.rotate(r)
+	}
+	start := len(l.buf)
+	l.buf = r.format(l, l.buf)
+
+	if l.maxSize > 0 && l.writtenSize+ByteSize(len(l.buf)) > l.maxSize {
+		// the records before r still go to the current file
+		l.writeBatch(l.buf[:start], l.last)
+		l.buf = append(l.buf[:0], l.buf[start:]...)
+		l.rotate(r)
+	}
+	l.last = r
+	if maxBatch, _ := l.batchOptions(); len(l.buf) >= maxBatch {
+		l.flushBatch()
+		return true
+	}
+	return false
+}
 
-	if l.writtenSize+size > l.maxSize {
-		l.rotate()
+// flushBatch writes the batch with one Write
+func (l *RotateLogger) flushBatch() {
+	l.writeBatch(l.buf, l.last)
+	l.buf, l.last = l.buf[:0], nil
+}
+
+func (l *RotateLogger) writeBatch(data []byte, r *Record) {
+	if len(data) == 0 {
+		return
+	}
+	if err := l.write(data); err != nil {
+		backendError(r, l, OpWrite, err)
 	}
-	_ = l.write(buf)
 }
 
-func (l *RotateLogger) rotate() {
+// rotate rotates the log files and applies the retention policy, r is the
+// record causing the rotation or nil. An empty file is reused.
+func (l *RotateLogger) rotate(r *Record) {
 
 	if l.out != nil {
 
 		if err := l.out.Close(); err != nil {
-			_, _ = fmt.Fprintf(os.Stderr, "error closing current writer : %v\n", err)
+			backendError(r, l, OpRotate, fmt.Errorf("error closing current writer : %v", err))
 			return
 		}
 	}
 
+	info, err := os.Stat(l.filename)
+	if err != nil || info.Size() == 0 {
+		l.reset()
+		return
+	}
+
+	var name string
+	if l.schedule.period == NoPeriod {
+		name = l.shift(r)
+	} else {
+		period := l.period
+		if l.out == nil {
+			// the file is left by a previous process
+			period = l.schedule.start(info.ModTime())
+		}
+		name = l.periodName(period)
+	}
+
+	if err := os.Rename(l.filename, name); err != nil {
+		backendError(r, l, OpRotate, fmt.Errorf("error moving current file : %v", err))
+	} else if l.compress > NoCompress {
+		l.queueArchive(name)
+	}
+	l.reset()
+	l.retain(r)
+}
+
+// shift renames the numbered files to make room for the current file and
+// returns its new name, the oldest file is replaced once maxFiles are kept.
+// The files waiting for compression are renamed too, the archiver names
+// their archive after their name once compressed.
+func (l *RotateLogger) shift(r *Record) string {
+	l.pendingMu.Lock()
+	defer l.pendingMu.Unlock()
 	if l.compress > NoCompress {
-		l.archive()
+		oldest := fmt.Sprintf("%s.%d", l.filename, l.maxFiles-1)
+		if job, ok := l.pending[oldest]; ok {
+			// replaced by the newer file, its archive is discarded
+			delete(l.pending, oldest)
+			job.name = ""
+			if err := os.Remove(oldest); err != nil && !os.IsNotExist(err) {
+				backendError(r, l, OpRotate, fmt.Errorf("error removing file %s : %v", oldest, err))
+			}
+		}
 	}
+	for i := l.maxFiles - 2; i >= 0; i-- {
+		if l.compress > NoCompress {
+			fileName := fmt.Sprintf("%s.%d", l.filename, i)
+			if _, ok := l.pending[fileName]; ok {
+				newFileName := fmt.Sprintf("%s.%d", l.filename, i+1)
+				if err := os.Rename(fileName, newFileName); err != nil {
+					backendError(r, l, OpRotate, fmt.Errorf("error moving current file : %v", err))
+				} else {
+					l.renamePending(fileName, newFileName)
+				}
+			}
+		}
 
-	// rotate file
-	for i := l.maxFiles - 1; i >= -1; i-- {
 		fileName := fmt.Sprintf("%s.%d%s", l.filename, i, l.compress)
 		newFileName := fmt.Sprintf("%s.%d%s", l.filename, i+1, l.compress)
 
-		if i == -1 {
-			fileName = fmt.Sprintf("%s%s", l.filename, l.compress)
-		}
-
 		_, err := os.Stat(fileName)
 
 		if err != nil && os.IsNotExist(err) {
@@ -220,10 +475,30 @@ func (l *RotateLogger) rotate() {
 
 		err = os.Rename(fileName, newFileName)
 		if err != nil && !os.IsNotExist(err) {
-			_, _ = fmt.Fprintf(os.Stderr, "error moving current file : %v\n", err)
+			backendError(r, l, OpRotate, fmt.Errorf("error moving current file : %v", err))
 		}
 	}
-	l.reset()
+	return fmt.Sprintf("%s.0", l.filename)
+}
+
+// periodName returns the first free name of a file of the period starting
+// at start: app.log.2026-10-17, app.log.2026-10-17.1...
+func (l *RotateLogger) periodName(start time.Time) string {
+	base := l.filename + "." + l.schedule.name(start)
+	for i := 0; ; i++ {
+		name := base
+		if i > 0 {
+			name = fmt.Sprintf("%s.%d", base, i)
+		}
+		if !fileExists(name) && !fileExists(name+l.compress.String()) {
+			return name
+		}
+	}
+}
+
+func fileExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil || !os.IsNotExist(err)
 }
 
 func (l *RotateLogger) reset()  {
@@ -234,54 +509,11 @@ func (l *RotateLogger) reset()  {
 	}
 	l.out = f
 	l.writtenSize = 0
-}
-
-// archive the always written log (l.filename) to l.filename.(gz/lz) when compress is requested
-func (l *RotateLogger) archive() {
-
-	in, err := os.OpenFile(l.filename, os.O_RDONLY, 0644)
-
-	// fail to open, such as file not exist or some other file system errors
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "error opening file %s : %v\n", l.filename, err)
-		return
-	}
-
-	defer in.Close()
-
-	reader := bufio.NewReader(in)
-	content, err := ioutil.ReadAll(reader)
-
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "error reading file %s : %v\n", l.filename, err)
-		return
-	}
-
-	compressedFileName := fmt.Sprintf("%s%s", l.filename, l.compress)
-
-	out, err := os.OpenFile(compressedFileName, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "error opening file %s : %v\n", compressedFileName, err)
-		return
+	if info, err := f.Stat(); err == nil {
+		l.writtenSize = ByteSize(info.Size())
 	}
-
-	// need compress, try to open
-	var w io.WriteCloser
-	switch l.compress {
-	case GZIP:
-		w = gzip.NewWriter(out)
-	case Zlib:
-		w = zlib.NewWriter(out)
-	case LZW:
-		w = lzw.NewWriter(out, lzw.MSB, 8)
-	default:
-		w = out
-	}
-
-	defer w.Close()
-	_, err = w.Write(content)
-
-	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "error commpressing file %s : %v\n", l.filename, err)
+	if l.schedule.period != NoPeriod {
+		l.period = l.schedule.start(l.clock.Now())
+		l.next = l.schedule.next(l.period)
 	}
 }
@@ -0,0 +1,370 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock set by the tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func TestRotateSchedule(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	tests := []struct {
+		opts             RotateOptions
+		now, start, next string
+		name             string
+	}{
+		{RotateOptions{Period: Hourly}, "2026-10-17 15:42", "2026-10-17 15:00", "2026-10-17 16:00", "2026-10-17T15"},
+		{RotateOptions{Period: Daily}, "2026-10-17 00:00", "2026-10-17 00:00", "2026-10-18 00:00", "2026-10-17"},
+		{RotateOptions{Period: Daily, At: 2 * time.Hour}, "2026-10-17 01:59", "2026-10-16 02:00", "2026-10-17 02:00", "2026-10-16"},
+		{RotateOptions{Period: Weekly, Weekday: time.Monday}, "2026-10-17 12:00", "2026-10-12 00:00", "2026-10-19 00:00", "2026-10-12"},
+		{RotateOptions{Period: Weekly, Weekday: time.Saturday, At: 3 * time.Hour}, "2026-10-17 01:00", "2026-10-10 03:00", "2026-10-17 03:00", "2026-10-10"},
+	}
+	for _, test := range tests {
+		test.opts.Location = loc
+		s := newRotateSchedule(test.opts)
+		start := s.start(at(test.now).UTC())
+		if !start.Equal(at(test.start)) || !s.next(start).Equal(at(test.next)) || s.name(start) != test.name {
+			t.Errorf("%+v at %s: got %s, %s, %s", test.opts, test.now, start, s.next(start), s.name(start))
+		}
+	}
+}
+
+func TestRotatePeriod(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)}
+	l := newRotateTestLogger(t, filename, RotateOptions{
+		MaxSize:  15,
+		Compress: GZIP,
+		Period:   Daily,
+		Location: time.UTC,
+		Clock:    clock,
+	})
+
+	l.Info("day 16")
+	l.Flush()
+	clock.Set(time.Date(2026, 10, 17, 0, 0, 1, 0, time.UTC))
+	for _, msg := range []string{"a", "b", "c"} {
+		l.Info(msg)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"app.log":                 "[ INFO] c\n",
+		"app.log.2026-10-16.gz":   "[ INFO] day 16\n",
+		"app.log.2026-10-17.gz":   "[ INFO] a\n",
+		"app.log.2026-10-17.1.gz": "[ INFO] b\n",
+	}
+	files, _ := filepath.Glob(filename + "*")
+	var names []string
+	for _, file := range files {
+		name := filepath.Base(file)
+		names = append(names, name)
+		content, err := readLogFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content != want[name] {
+			t.Errorf("%s: got %q; want %q", name, content, want[name])
+		}
+	}
+	if sort.Strings(names); len(names) != len(want) {
+		t.Errorf("got files %q", names)
+	}
+}
+
+// newRotateTestLogger creates a rotate logger without flags writing to
+// filename, it is closed at the end of the test
+func newRotateTestLogger(t *testing.T, filename string, opts RotateOptions) *Logger {
+	t.Helper()
+	opts.Filename = filename
+	l := NewRotateLoggerWithOptions(DEBUG, "", 0, opts)
+	t.Cleanup(func() { _ = l.Close(context.Background()) })
+	return l
+}
+
+// readLogFile returns the content of a log file, uncompressed if it is gzipped
+func readLogFile(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if !strings.HasSuffix(name, ".gz") {
+		data, err := ioutil.ReadAll(f)
+		return string(data), err
+	}
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadAll(r)
+	return string(data), err
+}
+
+func TestRotateSize(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	l := newRotateTestLogger(t, filename, RotateOptions{MaxFiles: 2, MaxSize: 15})
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		l.Info(msg)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filename + "*")
+	if len(files) != 3 {
+		t.Errorf("got files %q", files)
+	}
+	for suffix, want := range map[string]string{"": "d", ".0": "c", ".1": "b"} {
+		if content, err := readLogFile(filename + suffix); err != nil || content != "[ INFO] "+want+"\n" {
+			t.Errorf("app.log%s: got %q, %v; want %s", suffix, content, err, want)
+		}
+	}
+}
+
+func TestRotateCompressNumbered(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+	l := newRotateTestLogger(t, filename, RotateOptions{MaxFiles: 3, MaxSize: 15, Compress: GZIP})
+	for _, msg := range []string{"a", "b", "c", "d", "e", "f"} {
+		l.Info(msg)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filename + "*")
+	if len(files) != 4 {
+		t.Errorf("got files %q", files)
+	}
+	for suffix, want := range map[string]string{"": "f", ".0.gz": "e", ".1.gz": "d", ".2.gz": "c"} {
+		if content, err := readLogFile(filename + suffix); err != nil || content != "[ INFO] "+want+"\n" {
+			t.Errorf("app.log%s: got %q, %v; want %s", suffix, content, err, want)
+		}
+	}
+}
+
+func TestRotatedName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"app.log.0":                    true,
+		"app.log.12.gz":                true,
+		"app.log.3.zlib.tmp":           true,
+		"app.log.2026-10-17":           true,
+		"app.log.2026-10-17T15.lz":     true,
+		"app.log.2026-10-17.2.gz.tmp":  true,
+		"app.log.2026-10-17T15.1":      true,
+		"app.log":                      false,
+		"app.log.access":               false,
+		"app.log.1.bak":                false,
+		"app.log.2026-10-17.old":       false,
+		"app.log.2026-13-17":           false,
+		"app.logger.0":                 false,
+		"other.log.0":                  false,
+		"app.log.2026-10-17T15.1.gz.x": false,
+	} {
+		if got := rotatedName("app.log", name); got != want {
+			t.Errorf("rotatedName(%q) = %v; want %v", name, got, want)
+		}
+	}
+}
+
+func TestRotateRetention(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		opts RotateOptions
+		kept []string
+	}{
+		{RotateOptions{MaxAge: 36 * time.Hour}, []string{"app.log.2", "app.log.0"}},
+		{RotateOptions{MaxTotalSize: 30}, []string{"app.log.0"}},
+		{RotateOptions{MaxAge: 36 * time.Hour, MaxTotalSize: 30}, []string{"app.log.0"}},
+		{RotateOptions{MinFreeSpace: 1 << 60}, nil},
+	}
+	for _, test := range tests {
+		dir := t.TempDir()
+		filename := filepath.Join(dir, "app.log")
+		// a file of another logger and 3 days old, 2 days old and 1 day
+		// old files of 20 bytes
+		for i, name := range []string{"app.log.access", "app.log.3", "app.log.2", "app.log.1"} {
+			path := filepath.Join(dir, name)
+			if err := ioutil.WriteFile(path, make([]byte, 20), 0644); err != nil {
+				t.Fatal(err)
+			}
+			mtime := now.Add(time.Duration(i-4) * 24 * time.Hour)
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var deleted []string
+		test.opts.MaxSize = 10
+		test.opts.MaxFiles = 5
+		test.opts.Clock = &fakeClock{now: now}
+		test.opts.OnDelete = func(name, reason string) {
+			deleted = append(deleted, filepath.Base(name)+" "+reason)
+		}
+		l := newRotateTestLogger(t, filename, test.opts)
+		l.Info("rotated")
+		l.Info("current")
+		if err := l.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		files, _ := filepath.Glob(filename + ".*")
+		var kept []string
+		for _, file := range files {
+			if name := filepath.Base(file); name != "app.log.access" {
+				kept = append(kept, name)
+			}
+		}
+		if !fileExists(filename + ".access") {
+			t.Errorf("%+v: unrelated file deleted", test.opts)
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(kept)))
+		if strings.Join(kept, ",") != strings.Join(test.kept, ",") {
+			t.Errorf("%+v: kept %q; want %q, deleted %q", test.opts, kept, test.kept, deleted)
+		}
+		if len(deleted)+len(kept) != 4 {
+			t.Errorf("%+v: deleted %q", test.opts, deleted)
+		}
+	}
+}
+
+func TestRotateRetentionArchiving(t *testing.T) {
+	var errs []error
+	var mu sync.Mutex
+	SetErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	})
+	defer SetErrorHandler(nil)
+
+	filename := filepath.Join(t.TempDir(), "app.log")
+	var deleted []string
+	l := newRotateTestLogger(t, filename, RotateOptions{
+		MaxSize:      10,
+		Compress:     GZIP,
+		MinFreeSpace: 1 << 60,
+		OnDelete: func(name, reason string) {
+			deleted = append(deleted, name)
+		},
+	})
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		l.Info(msg)
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) > 0 {
+		t.Errorf("got errors %v", errs)
+	}
+	for _, name := range deleted {
+		if fileExists(name) {
+			t.Errorf("deleted file %s exists", name)
+		}
+	}
+}
+
+func TestRotateRecoverArchives(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	for name, content := range map[string]string{
+		"app.log.2026-10-15":        "uncompressed\n",
+		"app.log.2026-10-16.gz.tmp": "half-written",
+		"app.log.2026-10-16":        "interrupted\n",
+		"app.log.access":            "another logger\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := newRotateTestLogger(t, filename, RotateOptions{Compress: GZIP, Period: Daily})
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filename + ".*")
+	want := map[string]string{
+		"app.log.2026-10-15.gz": "uncompressed\n",
+		"app.log.2026-10-16.gz": "interrupted\n",
+		"app.log.access":        "another logger\n",
+	}
+	if len(files) != len(want) {
+		t.Errorf("got files %q", files)
+	}
+	for name, content := range want {
+		if got, err := readLogFile(filepath.Join(dir, name)); err != nil || got != content {
+			t.Errorf("%s: got %q, %v; want %q", name, got, err, content)
+		}
+	}
+}
+
+func TestRotateRecoverManyArchives(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	for i := 0; i < 2*maxPendingArchives; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("app.log.%d", i))
+		if err := ioutil.WriteFile(name, []byte("left\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	created := make(chan *Logger)
+	go func() {
+		created <- newRotateTestLogger(t, filename, RotateOptions{Compress: GZIP})
+	}()
+	select {
+	case l := <-created:
+		if err := l.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("recovery blocks the constructor")
+	}
+	if files, _ := filepath.Glob(filename + ".*.gz"); len(files) != 2*maxPendingArchives {
+		t.Errorf("got archives %q", files)
+	}
+}
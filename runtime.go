@@ -6,12 +6,19 @@ package log
 
 import (
 	"runtime"
+	"strconv"
 )
 
 // getRuntimeInfo returns function name, file name, file line of current call stack
 // if cannot get those info from runtime, will return a default value ??? for function
 // and file along with 0 for line
 func getRuntimeInfo(depth int) (string, string, int) {
+	_, function, fn, ln := callerInfo(depth + 1)
+	return function, fn, ln
+}
+
+// callerInfo returns program counter, function name, file name, file line of current call stack
+func callerInfo(depth int) (uintptr, string, string, int) {
 	pc, fn, ln, ok := runtime.Caller(depth)
 	if !ok {
 		fn = "???"
@@ -22,5 +29,49 @@ func getRuntimeInfo(depth int) (string, string, int) {
 	if caller != nil {
 		function = caller.Name()
 	}
-	return function, fn, ln
+	return pc, function, fn, ln
+}
+
+// frameInfo returns function name, file name, file line of the program counter pc
+// returned by runtime.Callers
+func frameInfo(pc uintptr) (string, string, int) {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if len(frame.File) == 0 {
+		return "???", "???", 0
+	}
+	return frame.Function, frame.File, frame.Line
+}
+
+// callers returns the frames of the call stack starting at depth, counted
+// like callerInfo, without the frame of runtime.goexit
+func callers(depth int) []runtime.Frame {
+	pcs := make([]uintptr, 32)
+	for {
+		n := runtime.Callers(depth+1, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, 2*len(pcs))
+	}
+	var stack []runtime.Frame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "runtime.goexit" && len(frame.File) > 0 {
+			stack = append(stack, frame)
+		}
+		if !more {
+			return stack
+		}
+	}
+}
+
+// formatStack formats each frame as function name followed by file:line
+func formatStack(frames []runtime.Frame) []string {
+	stack := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		stack = append(stack, frame.Function+" "+frame.File+":"+strconv.Itoa(frame.Line))
+	}
+	return stack
 }
@@ -0,0 +1,102 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"time"
+)
+
+// RotatePeriod represent the period of the time based rotation
+type RotatePeriod int
+
+// rotate period
+const (
+	NoPeriod RotatePeriod = iota
+	Hourly
+	Daily
+	Weekly
+)
+
+// Clock is the source of time of the rotation schedule, tests can use
+// their own clock to rotate without sleeping
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// rotateSchedule computes the periods covered by the rotated files
+type rotateSchedule struct {
+	period  RotatePeriod
+	at      time.Duration // offset of daily and weekly rotation from midnight
+	weekday time.Weekday  // day of weekly rotation
+	loc     *time.Location
+}
+
+func newRotateSchedule(opts RotateOptions) rotateSchedule {
+	s := rotateSchedule{
+		period:  opts.Period,
+		at:      opts.At % (24 * time.Hour),
+		weekday: opts.Weekday % 7,
+		loc:     opts.Location,
+	}
+	if s.at < 0 {
+		s.at += 24 * time.Hour
+	}
+	if s.loc == nil {
+		s.loc = time.Local
+	}
+	return s
+}
+
+// start returns the start of the period containing t
+func (s rotateSchedule) start(t time.Time) time.Time {
+	t = t.In(s.loc)
+	year, month, day := t.Date()
+	if s.period == Hourly {
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, s.loc)
+	}
+	days := 1
+	if s.period == Weekly {
+		days = 7
+		day -= int(t.Weekday()-s.weekday+7) % 7
+	}
+	start := s.dayAt(year, month, day)
+	if start.After(t) {
+		start = s.dayAt(year, month, day-days)
+	}
+	return start
+}
+
+// next returns the start of the period following the one starting at start
+func (s rotateSchedule) next(start time.Time) time.Time {
+	year, month, day := start.Date()
+	switch s.period {
+	case Hourly:
+		return start.Add(time.Hour)
+	case Weekly:
+		return s.dayAt(year, month, day+7)
+	default:
+		return s.dayAt(year, month, day+1)
+	}
+}
+
+// dayAt returns the rotation time of the given day
+func (s rotateSchedule) dayAt(year int, month time.Month, day int) time.Time {
+	at := int(s.at / time.Second)
+	return time.Date(year, month, day, at/3600, at/60%60, at%60, 0, s.loc)
+}
+
+// name returns the name of the period starting at start used in the file names
+func (s rotateSchedule) name(start time.Time) string {
+	if s.period == Hourly {
+		return start.Format("2006-01-02T15")
+	}
+	return start.Format("2006-01-02")
+}
@@ -0,0 +1,309 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// slogLevel maps a slog level onto TRACE..ERROR, records of slog never
+// exit the process so levels above slog.LevelError are written as ERROR
+func slogLevel(level slog.Level) Level {
+	switch {
+	case level < slog.LevelDebug:
+		return TRACE
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelWarn:
+		return INFO
+	case level < slog.LevelError:
+		return WARN
+	default:
+		return ERROR
+	}
+}
+
+// toSlogLevel maps a level onto a slog level, custom levels are mapped to
+// the slog level of the predefined level below them
+func toSlogLevel(level Level) slog.Level {
+	switch {
+	case level == none:
+		return slog.LevelInfo
+	case level > DEBUG:
+		return slog.LevelDebug - 4
+	case level > INFO:
+		return slog.LevelDebug
+	case level > WARN:
+		return slog.LevelInfo
+	case level > ERROR:
+		return slog.LevelWarn
+	case level > PANIC:
+		return slog.LevelError
+	default:
+		return slog.LevelError + 4
+	}
+}
+
+// groupOrAttrs is a group or the fields added by WithGroup or WithAttrs
+type groupOrAttrs struct {
+	group  string
+	fields []Field
+}
+
+// slogHandler is a slog.Handler writing to a Logger
+type slogHandler struct {
+	logger *Logger
+	goas   []groupOrAttrs
+}
+
+// NewSlogHandler returns a slog.Handler writing the records to l, the std
+// logger if l is nil. The slog levels are mapped onto TRACE..ERROR, attrs
+// onto fields and groups onto nested fields, the caller is taken from the
+// PC of the slog record.
+func NewSlogHandler(l *Logger) slog.Handler {
+	if l == nil {
+		l = std()
+	}
+	return &slogHandler{logger: l}
+}
+
+// Enabled implements the slog.Handler interface
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.mayBeEnabled(slogLevel(level))
+}
+
+// Handle implements the slog.Handler interface
+func (h *slogHandler) Handle(ctx context.Context, rec slog.Record) error {
+	l := h.logger
+	level := slogLevel(rec.Level)
+	if !l.enabledPC(level, rec.PC) {
+		return nil
+	}
+
+	var fields []Field
+	rec.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+	// the attrs of the record belong to the innermost group
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		if goa := h.goas[i]; len(goa.group) > 0 {
+			if len(fields) > 0 {
+				fields = []Field{{Key: goa.group, Value: fields}}
+			}
+		} else {
+			fields = joinFields(goa.fields, fields)
+		}
+	}
+
+	r := &Record{
+		time:   rec.Time,
+		level:  level,
+		pc:     rec.PC,
+		args:   []interface{}{rec.Message},
+		fields: joinFields(contextFields(ctx), fields),
+		mode:   writeModeLog,
+	}
+	l.lock()
+	defer l.unlock()
+	if l.flag&(Lshortfile|Llongfile|Lshortfunc|Llongfunc) != 0 {
+		var file, function string
+		function, file, r.line = frameInfo(rec.PC)
+		r.file, r.function = &file, &function
+	}
+	if l.wantStack(level) {
+		r.stack = stackFrom(rec.PC)
+	}
+	l.emit(r)
+	return nil
+}
+
+// WithAttrs implements the slog.Handler interface
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []Field
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{fields: fields})
+}
+
+// WithGroup implements the slog.Handler interface
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if len(name) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *slogHandler) with(goa groupOrAttrs) *slogHandler {
+	goas := make([]groupOrAttrs, 0, len(h.goas)+1)
+	goas = append(goas, h.goas...)
+	return &slogHandler{logger: h.logger, goas: append(goas, goa)}
+}
+
+// appendAttr appends a as field to fields, empty attrs and groups are
+// dropped and the attrs of a group without key are inlined
+func appendAttr(fields []Field, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(fields, Field{Key: a.Key, Value: a.Value.Any()})
+	}
+	var group []Field
+	for _, ga := range a.Value.Group() {
+		group = appendAttr(group, ga)
+	}
+	if len(group) == 0 {
+		return fields
+	}
+	if len(a.Key) == 0 {
+		return append(fields, group...)
+	}
+	return append(fields, Field{Key: a.Key, Value: group})
+}
+
+// fieldAttr converts f to an attr, a []Field value becomes a group
+func fieldAttr(f Field) slog.Attr {
+	nested, ok := f.Value.([]Field)
+	if !ok {
+		return slog.Any(f.Key, f.Value)
+	}
+	attrs := make([]slog.Attr, 0, len(nested))
+	for _, field := range nested {
+		attrs = append(attrs, fieldAttr(field))
+	}
+	return slog.Attr{Key: f.Key, Value: slog.GroupValue(attrs...)}
+}
+
+// SlogBackend writes the records through a slog.Handler, the handler
+// formats the records so the formatter and the Lxxx flags other than
+// Lloggername are not used
+type SlogBackend struct {
+	writeFailures
+	handler slog.Handler
+}
+
+// NewSlogLogger creates a new logger writing through h, the level of the
+// logger is TRACE so that h decides which records are written
+func NewSlogLogger(h slog.Handler, flag int) *Logger {
+	var name string
+
+	if flag&Lloggername > 0 {
+		name = procName()
+	}
+
+	l := &Logger{
+		backend: NewSlogBackend(h),
+		flag:    flag,
+		name:    name,
+		level:   TRACE,
+	}
+	logger.Store(l)
+	return l
+}
+
+// NewSlogBackend create a new backend writing to h
+func NewSlogBackend(h slog.Handler) Backend {
+	return &SlogBackend{handler: h}
+}
+
+// Handler returns the slog.Handler of current backend
+func (l *SlogBackend) Handler() slog.Handler {
+	return l.handler
+}
+
+// Writer returns an io.Writer writing each write as an info record
+func (l *SlogBackend) Writer() io.Writer {
+	return slogWriter{l}
+}
+
+// SetWriter is not supported by SlogBackend
+func (l *SlogBackend) SetWriter(io.Writer) {
+	// not supported
+}
+
+func (l *SlogBackend) write(data []byte) error {
+	if len(data) > 0 && data[len(data)-1] == '\n' {
+		data = data[:len(data)-1]
+	}
+	ctx := context.Background()
+	if !l.handler.Enabled(ctx, slog.LevelInfo) {
+		return nil
+	}
+	return l.handler.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, string(data), 0))
+}
+
+func (l *SlogBackend) log(r *Record) {
+	ctx := context.Background()
+	level := toSlogLevel(r.level)
+	if l.handler.Enabled(ctx, level) {
+		rec := slog.NewRecord(r.time, level, r.Message(), r.pc)
+		if r.flag&Lloggername != 0 && len(r.Name()) > 0 {
+			rec.AddAttrs(slog.String(JSONNameKey, r.Name()))
+		}
+		for _, field := range r.fields {
+			rec.AddAttrs(fieldAttr(field))
+		}
+		if len(r.stack) > 0 {
+			rec.AddAttrs(slog.Any(JSONStackKey, r.stack))
+		}
+		if err := l.handler.Handle(ctx, rec); err != nil {
+			backendError(r, l, OpWrite, err)
+		}
+	}
+}
+
+func (l *SlogBackend) start() {
+}
+
+// Flush the current log backend, the handler writes the records at once
+func (l *SlogBackend) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close the current log backend, the handler is not closed
+func (l *SlogBackend) Close(ctx context.Context) error {
+	return nil
+}
+
+// SetFormatter is not supported by SlogBackend, the handler formats the records
+func (l *SlogBackend) SetFormatter(Formatter) {
+	// not supported
+}
+
+func (l *SlogBackend) formatter() Formatter {
+	return nil
+}
+
+func (l *SlogBackend) isatty() bool {
+	return false
+}
+
+func (l *SlogBackend) fd() Handler {
+	return nil
+}
+
+// slogWriter is the io.Writer of SlogBackend
+type slogWriter struct {
+	backend *SlogBackend
+}
+
+func (w slogWriter) Write(p []byte) (int, error) {
+	if err := w.backend.write(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
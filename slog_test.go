@@ -0,0 +1,75 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"testing/slogtest"
+)
+
+func parseJSONLines(t *testing.T, b *bytes.Buffer) []map[string]interface{} {
+	var ms []map[string]interface{}
+	for _, line := range bytes.Split(b.Bytes(), []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(line, &m); err != nil {
+			t.Fatalf("invalid json %q: %v", line, err)
+		}
+		ms = append(ms, m)
+	}
+	return ms
+}
+
+func TestSlogHandler(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Ldate|Ltime)
+	l.SetFormatter(&JSONFormatter{TimeKey: slog.TimeKey})
+	defer l.SetFormatter(nil)
+
+	if err := slogtest.TestHandler(NewSlogHandler(l), func() []map[string]interface{} {
+		return parseJSONLines(t, &b)
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSlogBackend(t *testing.T) {
+	var b bytes.Buffer
+	l := NewSlogLogger(slog.NewJSONHandler(&b, nil), 0)
+
+	if err := slogtest.TestHandler(NewSlogHandler(l), func() []map[string]interface{} {
+		return parseJSONLines(t, &b)
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSlogLevels(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Lshortfile)
+	l.SetLogLevel(INFO)
+	s := slog.New(NewSlogHandler(l))
+
+	s.Debug("hidden")
+	s.Info("info", "k", 1)
+	s.Log(context.Background(), slog.LevelError+4, "clamped")
+	if s.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug enabled at INFO")
+	}
+
+	want := "[ INFO] slog_test.go:65: info k=1\n[ERROR] slog_test.go:66: clamped\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
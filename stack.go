@@ -0,0 +1,57 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+// StackLevel returns the least severe level whose records get the call
+// stack if Lstacktrace is set, ERROR by default
+func (l *Logger) StackLevel() Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stackThreshold()
+}
+
+// SetStackLevel sets the least severe level whose records get the call
+// stack if Lstacktrace is set, none restores ERROR. Records of Panic[f|ln]
+// always get the call stack.
+func (l *Logger) SetStackLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stackLevel = level
+}
+
+func (l *Logger) stackThreshold() Level {
+	if l.stackLevel == none {
+		return ERROR
+	}
+	return l.stackLevel
+}
+
+// wantStack reports whether a record at level gets the call stack, the
+// caller must hold the lock of l
+func (l *Logger) wantStack(level Level) bool {
+	return level == PANIC || l.flag&Lstacktrace != 0 && level > none && level <= l.stackThreshold()
+}
+
+// stackFrom returns the formatted call stack starting at the frame of the
+// program counter pc, the frames of the logger above it are skipped. It
+// returns nil if pc is 0 or not found in the call stack.
+func stackFrom(pc uintptr) []string {
+	if pc == 0 {
+		return nil
+	}
+	function, file, line := frameInfo(pc)
+	frames := callers(2)
+	for i, frame := range frames {
+		if frame.Function == function && frame.File == file && frame.Line == line {
+			return formatStack(frames[i:])
+		}
+	}
+	return nil
+}
+
+// SetStackLevel sets the stack level of the std logger
+func SetStackLevel(level Level) {
+	std().SetStackLevel(level)
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStackTrace(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Lstacktrace)
+	l.Warn("no stack")
+	l.Error("stack")
+	if l.StackLevel() != ERROR {
+		t.Errorf("got stack level %v; want ERROR", l.StackLevel())
+	}
+	l.SetStackLevel(WARN)
+	l.With("k", 1).Warn("child")
+	l.StdLogger(WARN).Print("stdlog")
+
+	records := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n[")
+	if len(records) != 4 || records[0] != "[ WARN] no stack" {
+		t.Fatalf("got %q", b.String())
+	}
+	for i, want := range []string{"ERROR] stack", " WARN] child k=1", " WARN] stdlog"} {
+		lines := strings.Split(records[i+1], "\n\t")
+		if lines[0] != want {
+			t.Errorf("got %q; want %q", lines[0], want)
+		}
+		if len(lines) < 2 || !strings.HasPrefix(lines[1], "github.com/mysqto/log.TestStackTrace ") {
+			t.Errorf("%s: got stack %q", want, lines[1:])
+		}
+	}
+}
+
+func TestLogfmtStack(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Lstacktrace)
+	l.SetFormatter(&LogfmtFormatter{})
+	l.Error("failed")
+	if got := b.String(); !strings.HasPrefix(got, `level=error msg=failed stack="github.com/mysqto/log.TestLogfmtStack `) ||
+		strings.Count(got, "\n") != 1 {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	stdlog "log"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stdWriter is the io.Writer of a stdlib logger, each write of the stdlib
+// logger is one line written as a record
+type stdWriter struct {
+	logger *Logger // nil for the std logger
+	level  Level
+}
+
+func (w *stdWriter) target() *Logger {
+	if w.logger != nil {
+		return w.logger
+	}
+	return std()
+}
+
+// Write implements the io.Writer interface, the file:line: header written
+// by stdlib loggers with Llongfile or Lshortfile is taken as caller
+func (w *stdWriter) Write(p []byte) (int, error) {
+	now := time.Now() // get this early.
+	l := w.target()
+	msg, file, line := parseStdLine(string(p))
+	var pc uintptr
+	var function string
+	if line > 0 {
+		pc, function = stdCaller(file, line)
+	}
+	if !l.enabledPC(w.level, pc) {
+		return len(p), nil
+	}
+
+	r := &Record{
+		time:  now,
+		level: w.level,
+		pc:    pc,
+		args:  []interface{}{msg},
+		mode:  writeModeLog,
+	}
+	if w.level == FATAL {
+		defer l.fatal()
+	}
+	l.lock()
+	defer l.unlock()
+	if l.flag&(Lshortfile|Llongfile|Lshortfunc|Llongfunc) != 0 {
+		if line == 0 {
+			file = "???"
+		}
+		if len(function) == 0 {
+			function = "???"
+		}
+		r.file, r.line, r.function = &file, line, &function
+	}
+	if l.wantStack(w.level) {
+		r.stack = stackFrom(pc)
+	}
+	l.emit(r)
+	return len(p), nil
+}
+
+// parseStdLine splits the file:line: header from a line of a stdlib logger,
+// line is 0 if there is no header
+func parseStdLine(s string) (msg, file string, line int) {
+	i := strings.Index(s, ": ")
+	if i <= 0 {
+		return s, "", 0
+	}
+	colon := strings.LastIndexByte(s[:i], ':')
+	if colon <= 0 {
+		return s, "", 0
+	}
+	n, err := strconv.Atoi(s[colon+1 : i])
+	if err != nil || n <= 0 {
+		return s, "", 0
+	}
+	return s[i+2:], s[:colon], n
+}
+
+// stdCaller returns the program counter and function name of the frame at
+// file:line in the current call stack, file may be a short file name
+func stdCaller(file string, line int) (uintptr, string) {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if frame.Line == line && (frame.File == file || splitLast(frame.File, '/') == file) {
+			return frame.PC + 1, frame.Function
+		}
+		if !more {
+			return 0, ""
+		}
+	}
+}
+
+// StdLogger returns a stdlib logger writing each line as a record at level
+// to l, for the APIs requiring a stdlib logger such as http.Server.ErrorLog.
+// The caller is kept as long as the flags of the stdlib logger are not changed.
+func (l *Logger) StdLogger(level Level) *stdlog.Logger {
+	return stdlog.New(&stdWriter{logger: l, level: level}, "", stdlog.Llongfile)
+}
+
+// RedirectStdLog redirects the output of the stdlib log package to l at
+// level, the std logger if l is nil. The returned function restores the
+// output, prefix and flags of the stdlib log package.
+func RedirectStdLog(l *Logger, level Level) func() {
+	out, prefix, flags := stdlog.Writer(), stdlog.Prefix(), stdlog.Flags()
+	stdlog.SetOutput(&stdWriter{logger: l, level: level})
+	stdlog.SetPrefix("")
+	stdlog.SetFlags(stdlog.Llongfile)
+	return func() {
+		stdlog.SetOutput(out)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetFlags(flags)
+	}
+}
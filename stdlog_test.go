@@ -0,0 +1,40 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"testing"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Lshortfile|Lshortfunc)
+	restore := RedirectStdLog(l, WARN)
+	stdlog.Printf("hello %d", 1)
+	restore()
+	if _, ok := stdlog.Writer().(*stdWriter); ok {
+		t.Error("stdlib log output not restored")
+	}
+
+	want := "[ WARN] stdlog_test.go:17:TestRedirectStdLog: hello 1\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	l.SetLogLevel(INFO)
+	l.StdLogger(DEBUG).Print("hidden")
+	l.StdLogger(ERROR).Print("a: b\nc")
+
+	want := "[ERROR] a: b\nc\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
@@ -5,16 +5,19 @@
 package log
 
 import (
+	"context"
 	"io"
 	"sync"
 )
 
 // SyncLog write log synchronously
 type SyncLog struct {
+	writeFailures
 	out     io.Writer
 	mu      sync.Mutex
 	handler Handler
 	istty   bool
+	format  Formatter
 }
 
 // NewSyncBackend create a new sync backend
@@ -57,9 +60,29 @@ func (l *SyncLog) log(r *Record) {
 func (l *SyncLog) start() {
 }
 
-// Flush the current log backend
-func (l *SyncLog) Flush() {
-	// nothing to do with current logger
+// Flush syncs the writer of current backend if it is a file
+func (l *SyncLog) Flush(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return syncWriter(l.out)
+}
+
+// Close flushes current backend, the writer is not closed
+func (l *SyncLog) Close(ctx context.Context) error {
+	return l.Flush(ctx)
+}
+
+// SetFormatter sets the formatter of current backend
+func (l *SyncLog) SetFormatter(f Formatter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = f
+}
+
+func (l *SyncLog) formatter() Formatter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.format
 }
 
 func (l *SyncLog) isatty() bool {
@@ -7,16 +7,21 @@
 package log
 
 import (
+	"bytes"
+	"context"
 	"io"
 	"log/syslog"
-	"os"
 	"sync"
 )
 
 // Syslog is the backend using syslog
 type Syslog struct {
-	out *syslog.Writer
-	mu  sync.Mutex
+	writeFailures
+	out      *syslog.Writer
+	mu       sync.Mutex
+	format   Formatter
+	once     sync.Once // closes out once
+	closeErr error
 }
 
 // NewSyslog crete a new logger using syslog backend with level/prefix/flag
@@ -42,25 +47,31 @@ func NewSyslog(level Level, prefix string, flag int) *Logger {
 	return l
 }
 
-// NewSyslogBackend creates a syslog backend
-func NewSyslogBackend(level Level, prefix string) (Backend, error) {
-	syslogLevel := syslog.LOG_INFO
-	switch level {
-	case FATAL:
-		syslogLevel = syslog.LOG_CRIT
-	case ERROR:
-		syslogLevel = syslog.LOG_ERR
-	case WARN:
-		syslogLevel = syslog.LOG_WARNING
-	case INFO:
-		syslogLevel = syslog.LOG_INFO
-	case DEBUG:
-		syslogLevel = syslog.LOG_DEBUG
+// severity maps a level to syslog severity, a custom level gets the severity
+// of the next less severe predefined level, the ones between WARN and INFO
+// are notices
+func severity(level Level) syslog.Priority {
+	switch {
+	case level == none:
+		return syslog.LOG_NOTICE
+	case level <= PANIC:
+		return syslog.LOG_CRIT
+	case level <= ERROR:
+		return syslog.LOG_ERR
+	case level <= WARN:
+		return syslog.LOG_WARNING
+	case level < INFO:
+		return syslog.LOG_NOTICE
+	case level == INFO:
+		return syslog.LOG_INFO
 	default:
-		syslogLevel = syslog.LOG_NOTICE
+		return syslog.LOG_DEBUG
 	}
+}
 
-	out, err := syslog.New(syslogLevel, prefix)
+// NewSyslogBackend creates a syslog backend
+func NewSyslogBackend(level Level, prefix string) (Backend, error) {
+	out, err := syslog.New(severity(level), prefix)
 
 	if err == nil {
 		return &Syslog{
@@ -86,31 +97,55 @@ func (l *Syslog) SetWriter(io.Writer) {
 func (l *Syslog) log(r *Record) {
 
 	// syslog writes log with newline, we don't need extra newline
-	r.newline = false
-	message := r.message()
-
-	switch r.level {
-	case FATAL:
-		_ = l.out.Crit(message)
-		os.Exit(-1)
-	case ERROR:
-		_ = l.out.Err(message)
-	case WARN:
-		_ = l.out.Warning(message)
-	case INFO:
-		_ = l.out.Info(message)
-	case DEBUG:
-		_ = l.out.Debug(message)
+	message := string(bytes.TrimRight(r.format(l, nil), "\n"))
+
+	var err error
+	switch severity(r.level) {
+	case syslog.LOG_CRIT:
+		err = l.out.Crit(message)
+	case syslog.LOG_ERR:
+		err = l.out.Err(message)
+	case syslog.LOG_WARNING:
+		err = l.out.Warning(message)
+	case syslog.LOG_INFO:
+		err = l.out.Info(message)
+	case syslog.LOG_DEBUG:
+		err = l.out.Debug(message)
 	default:
-		_ = l.out.Notice(message)
+		err = l.out.Notice(message)
+	}
+	if err != nil {
+		backendError(r, l, OpWrite, err)
 	}
 }
 
 func (l *Syslog) start() {
 }
 
-// Flush the current log backend
-func (l *Syslog) Flush() {
+// Flush the current log backend, syslog writes the records at once
+func (l *Syslog) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close closes the connection to the syslog daemon
+func (l *Syslog) Close(ctx context.Context) error {
+	l.once.Do(func() {
+		l.closeErr = l.out.Close()
+	})
+	return l.closeErr
+}
+
+// SetFormatter sets the formatter of current backend
+func (l *Syslog) SetFormatter(f Formatter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.format = f
+}
+
+func (l *Syslog) formatter() Formatter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.format
 }
 
 func (l *Syslog) isatty() bool {
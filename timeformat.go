@@ -0,0 +1,149 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"strconv"
+	"time"
+)
+
+// TimeFormat is the format of the record time. Besides the predefined
+// formats any time.Format layout can be used as TimeFormat.
+type TimeFormat string
+
+// predefined time formats, all of them except custom layouts are written
+// without calling time.Format
+const (
+	TimeDefault     TimeFormat = ""                              // 2009/01/23 01:23:23.123123 selected by Ldate, Ltime and Lmicroseconds
+	TimeRFC3339     TimeFormat = time.RFC3339                    // 2009-01-23T01:23:23+08:00
+	TimeRFC3339Nano TimeFormat = time.RFC3339Nano                // 2009-01-23T01:23:23.123123456+08:00
+	TimeISO8601     TimeFormat = "2006-01-02T15:04:05.000Z07:00" // 2009-01-23T01:23:23.123+08:00
+	TimeUnix        TimeFormat = "unix"                          // seconds since epoch: 1232644403
+	TimeUnixMilli   TimeFormat = "unixmilli"                     // milliseconds since epoch: 1232644403123
+	TimeUnixNano    TimeFormat = "unixnano"                      // nanoseconds since epoch: 1232644403123123456
+)
+
+// timeFormatNames are the names of the predefined time formats in layouts
+var timeFormatNames = map[string]TimeFormat{
+	"rfc3339":     TimeRFC3339,
+	"rfc3339nano": TimeRFC3339Nano,
+	"iso8601":     TimeISO8601,
+	"unix":        TimeUnix,
+	"unixmilli":   TimeUnixMilli,
+	"unixnano":    TimeUnixNano,
+}
+
+// isEpoch reports whether the format writes a number
+func (f TimeFormat) isEpoch() bool {
+	return f == TimeUnix || f == TimeUnixMilli || f == TimeUnixNano
+}
+
+// appendTime appends t formatted by format to buf, TimeDefault is written as TimeRFC3339Nano
+func appendTime(buf []byte, t time.Time, format TimeFormat) []byte {
+	switch format {
+	case TimeUnix:
+		return strconv.AppendInt(buf, t.Unix(), 10)
+	case TimeUnixMilli:
+		return strconv.AppendInt(buf, t.UnixNano()/int64(time.Millisecond), 10)
+	case TimeUnixNano:
+		return strconv.AppendInt(buf, t.UnixNano(), 10)
+	case TimeDefault, TimeRFC3339, TimeRFC3339Nano, TimeISO8601:
+	default:
+		return t.AppendFormat(buf, string(format))
+	}
+
+	year, month, day := t.Date()
+	if year < 0 || year > 9999 {
+		if format == TimeDefault {
+			format = TimeRFC3339Nano
+		}
+		return t.AppendFormat(buf, string(format))
+	}
+	hour, min, sec := t.Clock()
+	itoa(&buf, year, 4)
+	buf = append(buf, '-')
+	itoa(&buf, int(month), 2)
+	buf = append(buf, '-')
+	itoa(&buf, day, 2)
+	buf = append(buf, 'T')
+	itoa(&buf, hour, 2)
+	buf = append(buf, ':')
+	itoa(&buf, min, 2)
+	buf = append(buf, ':')
+	itoa(&buf, sec, 2)
+
+	switch format {
+	case TimeISO8601:
+		buf = append(buf, '.')
+		itoa(&buf, t.Nanosecond()/1e6, 3)
+	case TimeDefault, TimeRFC3339Nano:
+		if ns := t.Nanosecond(); ns != 0 {
+			// trailing zeros are trimmed like the .999999999 of time.RFC3339Nano
+			digits := 9
+			for ns%10 == 0 {
+				ns /= 10
+				digits--
+			}
+			buf = append(buf, '.')
+			itoa(&buf, ns, digits)
+		}
+	}
+
+	_, offset := t.Zone()
+	if offset == 0 {
+		return append(buf, 'Z')
+	}
+	zone := offset / 60 // minutes
+	if zone < 0 {
+		buf = append(buf, '-')
+		zone = -zone
+	} else {
+		buf = append(buf, '+')
+	}
+	itoa(&buf, zone/60, 2)
+	buf = append(buf, ':')
+	itoa(&buf, zone%60, 2)
+	return buf
+}
+
+// TimeFormat returns the time format of the logger
+func (l *Logger) TimeFormat() TimeFormat {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.timeFormat
+}
+
+// SetTimeFormat sets the time format of the logger, TimeDefault restores
+// the format selected by Ldate, Ltime and Lmicroseconds. The time is only
+// written if any of those flags is set.
+func (l *Logger) SetTimeFormat(format TimeFormat) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timeFormat = format
+}
+
+// Location returns the time zone of the logger, nil means local time or UTC if LUTC is set
+func (l *Logger) Location() *time.Location {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.location
+}
+
+// SetLocation sets the time zone of the record time, it takes precedence over LUTC
+func (l *Logger) SetLocation(loc *time.Location) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.location = loc
+}
+
+// SetTimeFormat sets the time format of the std logger
+func SetTimeFormat(format TimeFormat) {
+	std().SetTimeFormat(format)
+}
+
+// SetLocation sets the time zone of the std logger
+func SetLocation(loc *time.Location) {
+	std().SetLocation(loc)
+}
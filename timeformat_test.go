@@ -0,0 +1,71 @@
+// Copyright (c) 2019 Chen Lei <my@mysq.to>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendTime(t *testing.T) {
+	zones := []*time.Location{time.UTC, time.FixedZone("east", 8*3600+30*60), time.FixedZone("west", -5*3600)}
+	times := []time.Time{
+		time.Date(2009, 1, 23, 1, 23, 23, 0, time.UTC),
+		time.Date(2009, 1, 23, 1, 23, 23, 123000000, time.UTC),
+		time.Date(2019, 12, 31, 23, 59, 59, 123456789, time.UTC),
+		time.Date(1999, 7, 4, 0, 0, 0, 100, time.UTC),
+	}
+	for _, zone := range zones {
+		for _, tm := range times {
+			tm = tm.In(zone)
+			for _, format := range []TimeFormat{TimeRFC3339, TimeRFC3339Nano, TimeISO8601, "Jan _2 15:04:05"} {
+				if got, want := string(appendTime(nil, tm, format)), tm.Format(string(format)); got != want {
+					t.Errorf("%s: got %q; want %q", format, got, want)
+				}
+			}
+			epochs := map[TimeFormat]int64{
+				TimeUnix:      tm.Unix(),
+				TimeUnixMilli: tm.UnixNano() / 1e6,
+				TimeUnixNano:  tm.UnixNano(),
+			}
+			for format, want := range epochs {
+				if got := string(appendTime(nil, tm, format)); got != strconv.FormatInt(want, 10) {
+					t.Errorf("%s: got %q; want %d", format, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestTimeFormatAndLocation(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", Ldate|Ltime)
+	zone := time.FixedZone("test", -3*3600)
+	l.SetLocation(zone)
+	l.SetTimeFormat(TimeRFC3339)
+	l.Print("hello")
+	line := b.String()
+	tm, err := time.Parse(time.RFC3339, strings.Fields(line)[0])
+	if err != nil {
+		t.Fatalf("bad time in %q: %v", line, err)
+	}
+	if _, offset := tm.Zone(); offset != -3*3600 {
+		t.Errorf("got offset %d in %q", offset, line)
+	}
+	if want := " hello\n"; line[len(line)-len(want):] != want {
+		t.Errorf("got %q", line)
+	}
+
+	b.Reset()
+	l.SetFormatter(&JSONFormatter{TimeFormat: TimeUnix})
+	defer l.SetFormatter(nil)
+	l.Print("epoch")
+	if !bytes.HasPrefix(b.Bytes(), []byte(`{"ts":1`)) {
+		t.Errorf("epoch time not written as number: %q", b.String())
+	}
+}